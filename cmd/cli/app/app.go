@@ -2,6 +2,7 @@ package app
 
 import (
 	"github.com/Babatunde50/dockergen/cmd/cli/commands/initialize"
+	"github.com/Babatunde50/dockergen/cmd/cli/commands/lock"
 	"github.com/urfave/cli/v2"
 )
 
@@ -44,6 +45,7 @@ func NewApp() *cli.App {
 
 	app.Commands = []*cli.Command{
 		initialize.Command,
+		lock.Command,
 	}
 
 	return app
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/Babatunde50/dockergen/internal/detector"
 	"github.com/Babatunde50/dockergen/internal/generator"
+	"github.com/Babatunde50/dockergen/internal/imagelock"
 	"github.com/urfave/cli/v2"
 )
 
@@ -33,9 +35,42 @@ var Command = &cli.Command{
 		&cli.BoolFlag{
 			Name:    "multi-stage",
 			Aliases: []string{"m"},
-			Usage:   "Use multi-stage build for Go projects",
+			Usage:   "Use a multi-stage build",
 			Value:   true,
 		},
+		&cli.BoolFlag{
+			Name:  "per-binary",
+			Usage: "Write a Dockerfile.<name> per binary under cmd/ instead of one Dockerfile with a target per binary",
+		},
+		&cli.StringFlag{
+			Name:  "private-modules",
+			Usage: "Fetch private Go modules with BuildKit credentials: ssh (agent forwarding) or netrc (secret mount)",
+		},
+		&cli.BoolFlag{
+			Name:  "cache-mounts",
+			Usage: "Keep dependency and build caches in BuildKit cache mounts between builds",
+		},
+		&cli.StringSliceFlag{
+			Name:  "publish",
+			Usage: "Publish ports in docker-compose.yml as [host:]container; a mapping for the app port replaces the default",
+		},
+		&cli.StringFlag{
+			Name:  "compose-spec",
+			Usage: "docker-compose.yml format: compose (the Compose Specification), v2 (2.4) or v3 (3.8)",
+			Value: generator.ComposeSpecCompose,
+		},
+		&cli.StringSliceFlag{
+			Name:  "platforms",
+			Usage: "Target platforms for multi-architecture builds, e.g. linux/amd64,linux/arm64",
+		},
+		&cli.BoolFlag{
+			Name:  "pin",
+			Usage: fmt.Sprintf("Pin every base image to the digest recorded in %s", imagelock.FileName),
+		},
+		&cli.StringFlag{
+			Name:  "runtime-base",
+			Usage: fmt.Sprintf("Base image of the Go runtime stage in multi-stage builds: %s (default: alpine)", strings.Join(generator.RuntimeBases, ", ")),
+		},
 	},
 	Action: func(cCtx *cli.Context) error {
 
@@ -51,15 +86,79 @@ var Command = &cli.Command{
 
 		if cCtx.IsSet("port") {
 			project.Port = cCtx.Int("port")
+			project.PortSource = "--port flag"
+		}
+
+		fmt.Printf("🔌 Using port %d (%s)\n", project.Port, project.PortSource)
+
+		opts := generator.Options{
+			MultiStage: cCtx.Bool("multi-stage"),
+			PerBinary:  cCtx.Bool("per-binary") && len(project.Binaries) > 1,
+
+			PrivateModules: cCtx.String("private-modules"),
+			RuntimeBase:    cCtx.String("runtime-base"),
+			CacheMounts:    cCtx.Bool("cache-mounts"),
+			Platforms:      cCtx.StringSlice("platforms"),
+			Publish:        cCtx.StringSlice("publish"),
+			ComposeSpec:    cCtx.String("compose-spec"),
+		}
+
+		if cCtx.Bool("pin") {
+			opts.ImageLock, err = imagelock.Load(workDir)
+			if err != nil {
+				return err
+			}
+		}
+
+		if project.CGO && len(opts.Platforms) > 1 {
+			fmt.Println("⚠️  cgo cannot cross-compile, so buildx emulates platforms other than the build machine's")
+		}
+
+		if project.Vendored {
+			fmt.Println("📦 Building from vendor/ without downloading modules")
+		}
+
+		// Each Dockerfile builds one binary, or the whole project when binary is empty
+		type dockerfile struct {
+			name   string
+			binary string
+		}
+
+		dockerfiles := []dockerfile{{name: "Dockerfile"}}
+		if opts.PerBinary {
+			dockerfiles = nil
+			for _, b := range project.Binaries {
+				dockerfiles = append(dockerfiles, dockerfile{name: fmt.Sprintf("Dockerfile.%s", b.Name), binary: b.Name})
+			}
+		}
+
+		// Workspace projects are built from the workspace root, where a .dockerignore would apply to every
+		// module; BuildKit reads a <Dockerfile>.dockerignore next to the Dockerfile instead
+		contextDir := workDir
+		dockerignores := []string{".dockerignore"}
+		if project.Workspace != nil {
+			contextDir = project.Workspace.Root
+			dockerignores = nil
+			for _, d := range dockerfiles {
+				dockerignores = append(dockerignores, d.name+".dockerignore")
+			}
 		}
 
-		dockerfilePath := filepath.Join(workDir, "Dockerfile")
 		dockerComposeFilePath := filepath.Join(workDir, "docker-compose.yml")
 
 		if !cCtx.Bool("force") {
-			// Check if Dockerfile exists
-			if _, err := os.Stat(dockerfilePath); err == nil {
-				return fmt.Errorf("docker file already exists. use --force to overwrite")
+			// Check if any Dockerfile exists
+			for _, d := range dockerfiles {
+				if _, err := os.Stat(filepath.Join(workDir, d.name)); err == nil {
+					return fmt.Errorf("%s already exists. use --force to overwrite", d.name)
+				}
+			}
+
+			// Check if any .dockerignore exists
+			for _, name := range dockerignores {
+				if _, err := os.Stat(filepath.Join(workDir, name)); err == nil {
+					return fmt.Errorf("%s already exists. use --force to overwrite", name)
+				}
 			}
 
 			// Check if docker-compose.yml exists (if generation requested)
@@ -70,34 +169,90 @@ var Command = &cli.Command{
 			}
 		}
 
-		// Generate Dockerfile
-		dockerfileContent, err := generator.GenerateDockerfile(project, cCtx.Bool("multi-stage"))
+		// Generate every file before writing any, so an invalid option leaves the project untouched
+		type generatedFile struct {
+			name    string
+			content string
+			message string
+		}
+		var files []generatedFile
+
+		for _, d := range dockerfiles {
+			// Generate Dockerfile
+			dockerfileOpts := opts
+			dockerfileOpts.Binary = d.binary
+			dockerfileContent, err := generator.GenerateDockerfile(project, dockerfileOpts)
 
-		if err != nil {
-			return fmt.Errorf("failed to generate Dockerfile: %v", err)
+			if err != nil {
+				return fmt.Errorf("failed to generate %s: %v", d.name, err)
+			}
+
+			files = append(files, generatedFile{
+				name:    d.name,
+				content: dockerfileContent,
+				message: fmt.Sprintf("✅ Generated %s for %s project", d.name, project.Type),
+			})
 		}
 
-		// Write Dockerfile
-		err = os.WriteFile(dockerfilePath, []byte(dockerfileContent), 0644)
+		// Generate .dockerignore, seeded from the .gitignore at the root of the build context
+		gitignore, err := os.ReadFile(filepath.Join(contextDir, ".gitignore"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read .gitignore: %v", err)
+		}
+
+		dockerignoreContent, err := generator.GenerateDockerignore(project, string(gitignore))
 		if err != nil {
-			return fmt.Errorf("failed to write Dockerfile: %v", err)
+			return fmt.Errorf("failed to generate .dockerignore: %v", err)
+		}
+
+		for _, name := range dockerignores {
+			files = append(files, generatedFile{
+				name:    name,
+				content: dockerignoreContent,
+				message: fmt.Sprintf("✅ Generated %s for %s project", name, project.Type),
+			})
+		}
+
+		// Generate .env.example. Projects often keep a hand-written one, so it is left alone without --force
+		// instead of failing the whole run
+		if len(project.EnvVars) > 0 {
+			if _, err := os.Stat(filepath.Join(workDir, ".env.example")); err == nil && !cCtx.Bool("force") {
+				fmt.Println("⏭️  Keeping the existing .env.example, use --force to overwrite")
+			} else {
+				envExampleContent, err := generator.GenerateEnvExample(project)
+				if err != nil {
+					return fmt.Errorf("failed to generate .env.example: %v", err)
+				}
+
+				files = append(files, generatedFile{
+					name:    ".env.example",
+					content: envExampleContent,
+					message: fmt.Sprintf("✅ Generated .env.example with %d environment variables", len(project.EnvVars)),
+				})
+			}
 		}
-		fmt.Printf("✅ Generated Dockerfile for %s project\n", project.Type)
 
 		// Generate docker-compose.yml
 		if cCtx.Bool("compose") {
-			dockerComposeContent, err := generator.GenerateDockerCompose(getProjectName(project), fmt.Sprintf("%d", project.Port))
-
+			dockerComposeContent, err := generator.GenerateDockerCompose(getProjectName(project), project, opts)
 			if err != nil {
-				return fmt.Errorf("failed to write docker-compose.yml: %v", err)
+				return fmt.Errorf("failed to generate docker-compose.yml: %v", err)
 			}
 
-			err = os.WriteFile(dockerComposeFilePath, []byte(dockerComposeContent), 0644)
-			if err != nil {
-				return fmt.Errorf("failed to write docker-compose.yml: %v", err)
+			message := fmt.Sprintf("✅ Generated docker-compose.yml for %s project", project.Type)
+			for _, service := range project.Services {
+				message += fmt.Sprintf("\n🗄️  Added a %s service the app waits for", service)
 			}
 
-			fmt.Printf("✅ Generated docker-compose.yml for %s project\n", project.Type)
+			files = append(files, generatedFile{name: "docker-compose.yml", content: dockerComposeContent, message: message})
+		}
+
+		for _, f := range files {
+			err = os.WriteFile(filepath.Join(workDir, f.name), []byte(f.content), 0644)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %v", f.name, err)
+			}
+			fmt.Println(f.message)
 		}
 
 		fmt.Println("🚀 Dockerization complete!")
@@ -105,6 +260,9 @@ var Command = &cli.Command{
 	},
 }
 
+// invalidProjectNameChars matches the characters Docker Compose does not allow in project names
+var invalidProjectNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
 func getProjectName(project *detector.Project) string {
 
 	baseName := filepath.Base(project.WorkDir)
@@ -113,5 +271,13 @@ func getProjectName(project *detector.Project) string {
 	baseName = strings.ReplaceAll(baseName, " ", "-")
 	baseName = strings.ReplaceAll(baseName, "_", "-")
 
+	// Compose names must start with a letter or digit and only contain letters, digits, - and _
+	baseName = invalidProjectNameChars.ReplaceAllString(baseName, "-")
+	baseName = strings.Trim(baseName, "-_")
+
+	if baseName == "" {
+		return "app"
+	}
+
 	return baseName
 }
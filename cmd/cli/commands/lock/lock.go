@@ -0,0 +1,93 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Babatunde50/dockergen/internal/imagelock"
+	"github.com/urfave/cli/v2"
+)
+
+var Command = &cli.Command{
+	Name:  "lock",
+	Usage: fmt.Sprintf("Resolve the base images of the Dockerfiles to digests and record them in %s", imagelock.FileName),
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "registry",
+			Usage: "Resolve every image against this registry URL instead of its own, e.g. http://localhost:5000",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Give up on the registry after this long",
+			Value: 30 * time.Second,
+		},
+	},
+	Action: func(cCtx *cli.Context) error {
+
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %v", err)
+		}
+
+		dockerfiles, err := filepath.Glob(filepath.Join(workDir, "Dockerfile*"))
+		if err != nil {
+			return fmt.Errorf("failed to list Dockerfiles: %v", err)
+		}
+
+		// Read the images from the Dockerfiles on disk, so pinned and hand-edited files work too
+		seen := map[string]bool{}
+		var refs []string
+		for _, path := range dockerfiles {
+			if strings.HasSuffix(path, ".dockerignore") {
+				continue
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+			}
+
+			for _, ref := range imagelock.References(string(content)) {
+				if !seen[ref] {
+					seen[ref] = true
+					refs = append(refs, ref)
+				}
+			}
+		}
+
+		if len(refs) == 0 {
+			return fmt.Errorf("no base images found, run dockergen init first")
+		}
+
+		ctx, cancel := context.WithTimeout(cCtx.Context, cCtx.Duration("timeout"))
+		defer cancel()
+
+		resolver := &imagelock.Resolver{
+			Registry: cCtx.String("registry"),
+			Client:   http.DefaultClient,
+		}
+
+		lock := &imagelock.Lock{Images: map[string]string{}}
+		for _, ref := range refs {
+			digest, err := resolver.Resolve(ctx, ref)
+			if err != nil {
+				return err
+			}
+
+			lock.Images[ref] = digest
+			fmt.Printf("🔒 %s@%s\n", ref, digest)
+		}
+
+		if err := lock.Save(workDir); err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Wrote %s, run dockergen init --pin --force to use it\n", imagelock.FileName)
+		return nil
+	},
+}
@@ -2,7 +2,11 @@ module github.com/Babatunde50/dockergen
 
 go 1.23.2
 
-require github.com/urfave/cli/v2 v2.27.6
+require (
+	github.com/urfave/cli/v2 v2.27.6
+	golang.org/x/mod v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.5 // indirect
@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
@@ -17,12 +16,33 @@ const (
 	Python ProjectType = "python"
 )
 
+// PackageManager is the tool a project installs its dependencies with
+type PackageManager string
+
+// Binary is a main package under cmd/ that builds into its own executable
+type Binary struct {
+	Name       string // Executable name, taken from the package directory
+	Entrypoint string // File declaring func main, relative to WorkDir
+}
+
 type Project struct {
-	Type       ProjectType
-	Entrypoint string
-	Port       int
-	WorkDir    string
-	Version    string
+	Type           ProjectType
+	Entrypoint     string
+	Port           int
+	PortSource     string // Where the port was found, e.g. ".env" or "main.go:12"
+	WorkDir        string
+	Version        string
+	PackageManager PackageManager
+	LockFile       string // Lockfile (requirements.txt for pip) relative to WorkDir, empty when the project has none
+	Binaries       []Binary
+	Workspace      *GoWorkspace     // Set when a go.work in WorkDir or a parent directory covers the project
+	CGO            bool             // Set when the Go code imports "C" or depends on a module that needs cgo
+	Vendored       bool             // Set when dependencies are committed under vendor/
+	Installable    bool             // Set when a setup.py or pyproject.toml lets pip install the Python project
+	Metadata       Metadata         // Name, version, license and repository details for image labels
+	VersionVars    []VersionVar     // Go variables the build stamps with the version, commit and date
+	Services       []BackingService // Databases, caches and brokers the dependencies connect to
+	EnvVars        []EnvVar         // Environment variables the code reads, sorted by name
 }
 
 func DetectProject(rootDir string) (*Project, error) {
@@ -39,34 +59,67 @@ func DetectProject(rootDir string) (*Project, error) {
 	// Initialize project
 	project := &Project{
 		WorkDir: rootDir,
-		Port:    detectPort(rootDir),
 	}
 
 	// Check for project type
 	if isGoProject(rootDir) {
 		project.Type = Go
 		project.Entrypoint = findGoEntrypoint(rootDir)
+		project.Binaries = findGoBinaries(rootDir)
+		if project.Entrypoint == "" && len(project.Binaries) > 0 {
+			project.Entrypoint = project.Binaries[0].Entrypoint
+		}
 		project.Version = detectVersion(rootDir, Go)
+		project.CGO = usesCgo(rootDir)
+
+		mainFiles := []string{project.Entrypoint}
+		for _, b := range project.Binaries {
+			mainFiles = append(mainFiles, b.Entrypoint)
+		}
+		project.VersionVars = findVersionVars(rootDir, mainFiles)
+
+		// The go.work version applies to every module in the workspace
+		project.Workspace = findGoWorkspace(rootDir, project.Entrypoint)
+		if project.Workspace != nil && project.Workspace.Version != "" {
+			project.Version = project.Workspace.Version
+		}
+
+		// go work vendor keeps a single vendor directory at the workspace root
+		vendorDir := rootDir
+		if project.Workspace != nil {
+			vendorDir = project.Workspace.Root
+		}
+		project.Vendored = fileExists(filepath.Join(vendorDir, "vendor", "modules.txt"))
 	} else if isNodeJSProject(rootDir) {
 		project.Type = NodeJS
 		project.Entrypoint = findNodeJSEntrypoint(rootDir)
 		project.Version = detectVersion(rootDir, NodeJS)
+		project.PackageManager, project.LockFile = detectNodePackageManager(rootDir)
 	} else if isPythonProject(rootDir) {
 		project.Type = Python
 		project.Entrypoint = findPythonEntrypoint(rootDir)
 		project.Version = detectVersion(rootDir, Python)
+		project.PackageManager, project.LockFile = detectPythonPackageManager(rootDir)
+		project.Installable = fileExists(filepath.Join(rootDir, "setup.py")) || fileExists(filepath.Join(rootDir, "pyproject.toml"))
 	} else {
 		return nil, fmt.Errorf("unable to determine project type in %s", rootDir)
 	}
 
+	project.Metadata = detectMetadata(rootDir, project.Type)
+	project.Services = detectServices(rootDir, project.Type)
+	project.EnvVars = detectEnvVars(rootDir, project.Type)
+
+	project.Port, project.PortSource = detectPort(rootDir, project.Type, project.Entrypoint)
+
 	return project, nil
 }
 
 // isGoProject checks if the directory contains Go project indicators
 func isGoProject(dir string) bool {
-	// Look for go.mod, go.sum
+	// Look for go.mod, go.sum, go.work
 	if fileExists(filepath.Join(dir, "go.mod")) ||
-		fileExists(filepath.Join(dir, "go.sum")) {
+		fileExists(filepath.Join(dir, "go.sum")) ||
+		fileExists(filepath.Join(dir, "go.work")) {
 		return true
 	}
 
@@ -95,11 +148,22 @@ func isNodeJSProject(dir string) bool {
 
 // isPythonProject checks if the directory contains Python project indicators
 func isPythonProject(dir string) bool {
-	// Look for requirements.txt, setup.py, or Pipfile
-	if fileExists(filepath.Join(dir, "requirements.txt")) ||
-		fileExists(filepath.Join(dir, "setup.py")) ||
-		fileExists(filepath.Join(dir, "Pipfile")) {
-		return true
+	// Look for dependency manifests and lockfiles of the common Python package managers
+	indicators := []string{
+		"requirements.txt",
+		"setup.py",
+		"pyproject.toml",
+		"Pipfile",
+		"Pipfile.lock",
+		"poetry.lock",
+		"uv.lock",
+		"pdm.lock",
+	}
+
+	for _, indicator := range indicators {
+		if fileExists(filepath.Join(dir, indicator)) {
+			return true
+		}
 	}
 
 	// Check for .py files
@@ -126,30 +190,20 @@ func findGoEntrypoint(dir string) string {
 	}
 
 	for _, candidate := range candidates {
-		if fileExists(candidate) {
+		if isGoMainFile(candidate) {
 			relPath, _ := filepath.Rel(dir, candidate)
 			return relPath
 		}
 	}
 
-	// Look for any file with a main function
+	// Look for any file of package main that declares the main function
 	var mainFile string
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		if strings.Contains(string(content), "func main()") {
+	walkSourceFiles(dir, []string{".go"}, func(path string) bool {
+		if isGoMainFile(path) {
 			mainFile = path
-			return filepath.SkipDir // Stop walking once found
+			return false
 		}
-
-		return nil
+		return true
 	})
 
 	if mainFile != "" {
@@ -241,33 +295,6 @@ func findPythonEntrypoint(dir string) string {
 	return ""
 }
 
-// detectPort tries to find the port number used in the project looking for the PORT variable in the environment files
-func detectPort(dir string) int {
-
-	// First check environment files
-	envFiles := []string{
-		filepath.Join(dir, ".env"),
-		filepath.Join(dir, ".env.development"),
-		filepath.Join(dir, ".env.local"),
-	}
-
-	for _, envFile := range envFiles {
-		if fileExists(envFile) {
-			content, err := os.ReadFile(envFile)
-			if err == nil {
-				portEnvPattern := regexp.MustCompile(`PORT\s*=\s*(\d+)`)
-				matches := portEnvPattern.FindStringSubmatch(string(content))
-				if len(matches) > 1 {
-					port, _ := strconv.Atoi(matches[1])
-					return port
-				}
-			}
-		}
-	}
-
-	return 3000
-}
-
 // Helper functions
 func fileExists(filepath string) bool {
 	info, err := os.Stat(filepath)
@@ -285,38 +312,118 @@ func dirExists(filepath string) bool {
 	return info.IsDir()
 }
 
+// skippedDirs are never searched for source files
+var skippedDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+	"venv":         true,
+	"__pycache__":  true,
+	"dist":         true,
+	"build":        true,
+}
+
+// walkSourceFiles calls fn for every file under dir with one of the given extensions in lexical order,
+// skipping hidden, vendored and build output directories. The walk stops when fn returns false.
+func walkSourceFiles(dir string, extensions []string, fn func(path string) bool) {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || skippedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		for _, extension := range extensions {
+			if strings.HasSuffix(path, extension) {
+				if !fn(path) {
+					return filepath.SkipAll
+				}
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+// readVersionFile returns the first non-comment line of a version file such as .nvmrc
+func readVersionFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+
+	return ""
+}
+
+// readToolVersion returns the first version listed for any of the tools in an asdf/mise .tool-versions file
+func readToolVersion(dir string, tools ...string) string {
+	content, err := os.ReadFile(filepath.Join(dir, ".tool-versions"))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, tool := range tools {
+			if fields[0] == tool {
+				return fields[1]
+			}
+		}
+	}
+
+	return ""
+}
+
 // detectVersion attempts to extract the version from the project
 func detectVersion(dir string, projectType ProjectType) string {
 	switch projectType {
 	case Go:
 		return detectGoVersion(dir)
 	case NodeJS:
-		// TODO: Implement Node.js version detection
-		return ""
+		return detectNodeVersion(dir)
 	case Python:
-		// TODO: Implement Python version detection
-		return ""
+		return detectPythonVersion(dir)
 	default:
 		return ""
 	}
 }
 
+// detectGoVersion returns the full Go release to build with, preferring the go.mod toolchain
+// directive (toolchain go1.23.1) over the go directive (go 1.22.3)
 func detectGoVersion(dir string) string {
 	// Default Go version if we can't detect it
 	defaultVersion := "1.22"
 
-	// Try to read go.mod file
-	goModPath := filepath.Join(dir, "go.mod")
-	content, err := os.ReadFile(goModPath)
-	if err != nil {
+	goMod := readGoMod(dir)
+	if goMod == nil {
 		return defaultVersion
 	}
 
-	// Parse go.mod for the Go version using regex
-	re := regexp.MustCompile(`go\s+(\d+\.\d+)`)
-	matches := re.FindStringSubmatch(string(content))
-	if len(matches) > 1 {
-		return matches[1]
+	if goMod.Toolchain != nil {
+		if matches := goToolchainPattern.FindStringSubmatch(goMod.Toolchain.Name); matches != nil {
+			return matches[1]
+		}
+	}
+
+	if goMod.Go != nil && goMod.Go.Version != "" {
+		return goMod.Go.Version
 	}
 
 	return defaultVersion
@@ -0,0 +1,269 @@
+package detector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvVar is an environment variable the project reads
+type EnvVar struct {
+	Name    string
+	Default string // Fallback written next to the lookup, empty when the code has none
+	Source  string // Where the variable was found, e.g. "config.go:12" or ".env"
+}
+
+// envPatterns match environment lookups in source code. The first group is the variable name, the
+// first non-empty group after it the default value.
+var envPatterns = map[ProjectType][]*regexp.Regexp{
+	Go: {
+		regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\(\s*"(\w+)"\s*\)`),
+		regexp.MustCompile(`cmp\.Or\(\s*os\.Getenv\("(\w+)"\)\s*,\s*"([^"]*)"`),
+		regexp.MustCompile(`\b(?:[gG]et|[lL]ookup|[mM]ust)?[Ee]nv\w*\(\s*"([A-Z][A-Z0-9_]*)"\s*,\s*"([^"]*)"`),
+		regexp.MustCompile(`viper\.BindEnv\(\s*"[^"]*"\s*,\s*"(\w+)"`),
+	},
+	NodeJS: {
+		regexp.MustCompile(`process\.env\.([A-Za-z_]\w*)(?:\s*(?:\|\||\?\?)\s*(?:['"]([^'"]*)['"]|(\d+(?:\.\d+)?)|(true|false)))?`),
+		regexp.MustCompile(`process\.env\[\s*['"](\w+)['"]\s*\](?:\s*(?:\|\||\?\?)\s*(?:['"]([^'"]*)['"]|(\d+(?:\.\d+)?)|(true|false)))?`),
+	},
+	Python: {
+		regexp.MustCompile(`os\.environ\[\s*['"](\w+)['"]\s*\]`),
+		regexp.MustCompile(`os\.(?:environ\.get|getenv)\(\s*['"](\w+)['"]\s*(?:,\s*(?:['"]([^'"]*)['"]|(\d+(?:\.\d+)?)|(True|False)))?`),
+	},
+}
+
+var (
+	// goGetenvPattern finds `x := os.Getenv("X")` so a fallback assigned to x can be looked up
+	goGetenvPattern = regexp.MustCompile(`(\w+)\s*:?=\s*os\.Getenv\("(\w+)"\)`)
+
+	// goStructTagPattern finds the struct tags of envconfig and caarlos0/env fields
+	goStructTagPattern = regexp.MustCompile("`([^`]*\\b(?:env|envconfig):\"[^`]*)`")
+
+	envconfigPrefixPattern = regexp.MustCompile(`envconfig\.(?:Process|MustProcess)\(\s*"(\w+)"`)
+
+	// viper reads every key through the environment once AutomaticEnv is called
+	viperKeyPattern     = regexp.MustCompile(`viper\.(?:Get\w*|SetDefault)\(\s*"([\w.]+)"(?:\s*,\s*(?:"([^"]*)"|(\d+(?:\.\d+)?)|(true|false)))?`)
+	viperPrefixPattern  = regexp.MustCompile(`viper\.SetEnvPrefix\(\s*"(\w+)"`)
+	viperBindEnvPattern = regexp.MustCompile(`viper\.BindEnv\(\s*"(\w+)"\s*\)`)
+
+	pydanticClassPattern  = regexp.MustCompile(`^class\s+\w+\([^)]*\bBaseSettings\b[^)]*\)\s*:`)
+	pydanticFieldPattern  = regexp.MustCompile(`^([a-z_]\w*)\s*:\s*[^=]+?(?:=\s*(.+?))?\s*(?:#.*)?$`)
+	pydanticPrefixPattern = regexp.MustCompile(`env_prefix\s*=\s*['"](\w*)['"]`)
+	pythonLiteralPattern  = regexp.MustCompile(`^(?:Field\(\s*(?:default\s*=\s*)?)?(?:['"]([^'"]*)['"]|(\d+(?:\.\d+)?)|(True|False))`)
+
+	dotenvKeyPattern = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_]\w*)\s*=`)
+)
+
+// ignoredEnvVars are set by the shell or the container runtime rather than by the developer
+var ignoredEnvVars = map[string]bool{
+	"HOME":     true,
+	"PATH":     true,
+	"PWD":      true,
+	"USER":     true,
+	"SHELL":    true,
+	"HOSTNAME": true,
+	"TMPDIR":   true,
+	"TERM":     true,
+	"LANG":     true,
+	"CI":       true,
+}
+
+// detectEnvVars finds the environment variables the project reads, from lookups in the source, the
+// config structs of envconfig, caarlos0/env, viper and pydantic, and the keys of a .env file loaded
+// with dotenv. Values in .env are never copied since they are often secrets.
+func detectEnvVars(dir string, projectType ProjectType) []EnvVar {
+	found := map[string]EnvVar{}
+
+	add := func(name, value, source string) {
+		if ignoredEnvVars[name] {
+			return
+		}
+		existing, ok := found[name]
+		if !ok || (existing.Default == "" && value != "") {
+			found[name] = EnvVar{Name: name, Default: value, Source: source}
+		}
+	}
+
+	walkSourceFiles(dir, sourceExtensions[projectType], func(path string) bool {
+		if strings.HasSuffix(path, "_test.go") {
+			return true
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return true
+		}
+		content := string(data)
+
+		relPath, _ := filepath.Rel(dir, path)
+		source := func(index int) string {
+			return fmt.Sprintf("%s:%d", filepath.ToSlash(relPath), strings.Count(content[:index], "\n")+1)
+		}
+
+		for _, pattern := range envPatterns[projectType] {
+			for _, loc := range pattern.FindAllStringSubmatchIndex(content, -1) {
+				add(content[loc[2]:loc[3]], firstGroup(content, loc, 2), source(loc[0]))
+			}
+		}
+
+		switch projectType {
+		case Go:
+			findGoEnvVars(content, source, add)
+		case Python:
+			findPydanticEnvVars(content, source, add)
+		}
+
+		return true
+	})
+
+	if content, err := os.ReadFile(filepath.Join(dir, ".env")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if matches := dotenvKeyPattern.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+				add(matches[1], "", ".env")
+			}
+		}
+	}
+
+	envVars := make([]EnvVar, 0, len(found))
+	for _, envVar := range found {
+		envVars = append(envVars, envVar)
+	}
+	sort.Slice(envVars, func(i, j int) bool { return envVars[i].Name < envVars[j].Name })
+
+	return envVars
+}
+
+// findGoEnvVars finds the fallbacks assigned after os.Getenv and the variables of config libraries
+func findGoEnvVars(content string, source func(int) string, add func(name, value, source string)) {
+	// value := os.Getenv("X"); if value == "" { value = "default" }
+	for _, loc := range goGetenvPattern.FindAllStringSubmatchIndex(content, -1) {
+		variable, name := content[loc[2]:loc[3]], content[loc[4]:loc[5]]
+		fallback := regexp.MustCompile(`\b` + regexp.QuoteMeta(variable) + `\s*=\s*"([^"]*)"`)
+
+		rest := content[loc[1]:]
+		if len(rest) > 300 {
+			rest = rest[:300]
+		}
+		if match := fallback.FindStringSubmatch(rest); match != nil {
+			add(name, match[1], source(loc[0]))
+		}
+	}
+
+	envconfigPrefix := ""
+	if matches := envconfigPrefixPattern.FindStringSubmatch(content); matches != nil {
+		envconfigPrefix = strings.ToUpper(matches[1]) + "_"
+	}
+
+	for _, loc := range goStructTagPattern.FindAllStringSubmatchIndex(content, -1) {
+		tag := reflect.StructTag(content[loc[2]:loc[3]])
+
+		if name, _, _ := strings.Cut(tag.Get("env"), ","); name != "" {
+			add(name, tag.Get("envDefault"), source(loc[0]))
+		}
+		if name := tag.Get("envconfig"); name != "" {
+			add(envconfigPrefix+name, tag.Get("default"), source(loc[0]))
+		}
+	}
+
+	for _, loc := range viperBindEnvPattern.FindAllStringSubmatchIndex(content, -1) {
+		add(strings.ToUpper(content[loc[2]:loc[3]]), "", source(loc[0]))
+	}
+
+	if !strings.Contains(content, "viper.AutomaticEnv()") {
+		return
+	}
+
+	viperPrefix := ""
+	if matches := viperPrefixPattern.FindStringSubmatch(content); matches != nil {
+		viperPrefix = strings.ToUpper(matches[1]) + "_"
+	}
+	replacesDots := strings.Contains(content, "viper.SetEnvKeyReplacer(")
+
+	for _, loc := range viperKeyPattern.FindAllStringSubmatchIndex(content, -1) {
+		key := content[loc[2]:loc[3]]
+		if strings.Contains(key, ".") {
+			// Without a key replacer nested keys map to names no shell can export
+			if !replacesDots {
+				continue
+			}
+			key = strings.ReplaceAll(key, ".", "_")
+		}
+		add(viperPrefix+strings.ToUpper(key), firstGroup(content, loc, 2), source(loc[0]))
+	}
+}
+
+// findPydanticEnvVars reads the fields of BaseSettings classes, which pydantic matches to environment
+// variables by name regardless of case, after the env_prefix of the class
+func findPydanticEnvVars(content string, source func(int) string, add func(name, value, source string)) {
+	lines := strings.Split(content, "\n")
+
+	offsets := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		offsets[i] = offsets[i-1] + len(lines[i-1]) + 1
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if !pydanticClassPattern.MatchString(lines[i]) {
+			continue
+		}
+
+		// The class body is every line indented deeper than the class statement
+		classIndent := indentation(lines[i])
+		end := i + 1
+		for end < len(lines) && (strings.TrimSpace(lines[end]) == "" || indentation(lines[end]) > classIndent) {
+			end++
+		}
+
+		prefix := ""
+		for _, line := range lines[i+1 : end] {
+			if matches := pydanticPrefixPattern.FindStringSubmatch(line); matches != nil {
+				prefix = matches[1]
+			}
+		}
+
+		bodyIndent := -1
+		for j := i + 1; j < end; j++ {
+			line := lines[j]
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if bodyIndent == -1 {
+				bodyIndent = indentation(line)
+			}
+			if indentation(line) != bodyIndent {
+				continue
+			}
+
+			matches := pydanticFieldPattern.FindStringSubmatch(strings.TrimSpace(line))
+			if matches == nil || matches[1] == "model_config" {
+				continue
+			}
+
+			value := ""
+			if literal := pythonLiteralPattern.FindStringSubmatchIndex(matches[2]); literal != nil {
+				value = firstGroup(matches[2], literal, 1)
+			}
+			add(strings.ToUpper(prefix+matches[1]), value, source(offsets[j]))
+		}
+
+		i = end - 1
+	}
+}
+
+// firstGroup returns the first non-empty submatch from group on
+func firstGroup(s string, loc []int, group int) string {
+	for i := group * 2; i+1 < len(loc); i += 2 {
+		if loc[i] >= 0 && loc[i+1] > loc[i] {
+			return s[loc[i]:loc[i+1]]
+		}
+	}
+	return ""
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
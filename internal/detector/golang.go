@@ -0,0 +1,442 @@
+package detector
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// cgoModules are modules that only build with cgo enabled
+var cgoModules = []string{
+	"github.com/mattn/go-sqlite3",
+	"github.com/mattn/go-oci8",
+	"github.com/godror/godror",
+	"github.com/confluentinc/confluent-kafka-go",
+	"github.com/linxGnu/grocksdb",
+	"github.com/tecbot/gorocksdb",
+	"github.com/DataDog/zstd",
+	"github.com/valyala/gozstd",
+	"github.com/h2non/bimg",
+	"github.com/davidbyttow/govips",
+	"gopkg.in/gographics/imagick.v3",
+	"github.com/go-gl/glfw",
+}
+
+// versionVarArgs maps the lowercase names of common build metadata variables to the build arg that sets them
+var versionVarArgs = map[string]string{
+	"version":        "VERSION",
+	"appversion":     "VERSION",
+	"buildversion":   "VERSION",
+	"gitversion":     "VERSION",
+	"commit":         "COMMIT",
+	"gitcommit":      "COMMIT",
+	"commithash":     "COMMIT",
+	"revision":       "COMMIT",
+	"gitrevision":    "COMMIT",
+	"sha":            "COMMIT",
+	"gitsha":         "COMMIT",
+	"date":           "DATE",
+	"builddate":      "DATE",
+	"buildtime":      "DATE",
+	"buildtimestamp": "DATE",
+}
+
+// versionPackages are the directory names of packages that conventionally hold build metadata
+var versionPackages = map[string]bool{"version": true, "buildinfo": true}
+
+// ldflagsSafeValuePattern matches defaults that can go into an -X flag without quoting
+var ldflagsSafeValuePattern = regexp.MustCompile(`^[\w.+-]*$`)
+
+// goToolchainPattern extracts the release from toolchain names such as go1.23.1, dropping custom suffixes
+var goToolchainPattern = regexp.MustCompile(`^go(\d+\.\d+(?:\.\d+|rc\d+)?)`)
+
+// linuxBuildContext evaluates build constraints the way they apply inside a Linux container
+var linuxBuildContext = func() build.Context {
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	return ctx
+}()
+
+// VersionVar is a package-level string variable the build sets with -ldflags -X
+type VersionVar struct {
+	Symbol  string // Import path and name, e.g. main.version
+	Arg     string // Build arg providing the value: VERSION, COMMIT or DATE
+	Default string // Value in the source, kept when the build arg is empty
+}
+
+// GoWorkspace describes the go.work workspace a Go project belongs to
+type GoWorkspace struct {
+	Root    string   // Absolute path of the directory holding go.work
+	Modules []string // Module directories the build needs, relative to Root and slash-separated
+	Module  string   // Directory of the selected module, relative to Root and slash-separated
+	Version string   // Go release from the go.work toolchain or go directive
+}
+
+// findGoWorkspace looks for a go.work in dir and its parents. The selected module is the workspace
+// module containing dir or, when dir is the workspace root, the one holding the entrypoint.
+func findGoWorkspace(dir, entrypoint string) *GoWorkspace {
+	root := dir
+	for !fileExists(filepath.Join(root, "go.work")) {
+		parent := filepath.Dir(root)
+		if parent == root {
+			return nil
+		}
+		root = parent
+	}
+
+	path := filepath.Join(root, "go.work")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	work, err := modfile.ParseWork(path, content, nil)
+	if err != nil {
+		return nil
+	}
+
+	workspace := &GoWorkspace{Root: root}
+
+	if work.Toolchain != nil {
+		if matches := goToolchainPattern.FindStringSubmatch(work.Toolchain.Name); matches != nil {
+			workspace.Version = matches[1]
+		}
+	}
+	if workspace.Version == "" && work.Go != nil {
+		workspace.Version = work.Go.Version
+	}
+
+	for _, use := range work.Use {
+		workspace.addModule(filepath.Join(root, filepath.FromSlash(use.Path)))
+	}
+
+	// Modules replaced with local directories have to be in the build context as well
+	for i := 0; i < len(workspace.Modules); i++ {
+		moduleDir := filepath.Join(root, filepath.FromSlash(workspace.Modules[i]))
+		goMod := readGoMod(moduleDir)
+		if goMod == nil {
+			continue
+		}
+
+		for _, replace := range goMod.Replace {
+			if replace.New.Version == "" && modfile.IsDirectoryPath(replace.New.Path) {
+				workspace.addModule(filepath.Join(moduleDir, filepath.FromSlash(replace.New.Path)))
+			}
+		}
+	}
+
+	// Prefer the innermost module containing dir, then the one containing the entrypoint
+	workspace.Module = workspace.moduleContaining(dir)
+	if workspace.Module == "" && entrypoint != "" {
+		workspace.Module = workspace.moduleContaining(filepath.Join(dir, entrypoint))
+	}
+	if workspace.Module == "" && len(workspace.Modules) > 0 {
+		workspace.Module = workspace.Modules[0]
+	}
+
+	return workspace
+}
+
+// addModule records a module directory if it is inside the workspace and not yet known
+func (w *GoWorkspace) addModule(moduleDir string) {
+	rel, err := filepath.Rel(w.Root, moduleDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+
+	rel = filepath.ToSlash(rel)
+	for _, existing := range w.Modules {
+		if existing == rel {
+			return
+		}
+	}
+
+	w.Modules = append(w.Modules, rel)
+}
+
+// moduleContaining returns the innermost workspace module that contains path
+func (w *GoWorkspace) moduleContaining(path string) string {
+	rel, err := filepath.Rel(w.Root, path)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	best := ""
+	for _, module := range w.Modules {
+		inside := module == "." || rel == module || strings.HasPrefix(rel, module+"/")
+		if inside && (best == "" || best == "." || len(module) > len(best)) {
+			best = module
+		}
+	}
+
+	return best
+}
+
+// readGoMod parses the go.mod in dir, returning nil if it is missing or invalid
+func readGoMod(dir string) *modfile.File {
+	path := filepath.Join(dir, "go.mod")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	// Strict parsing keeps main-module directives like toolchain and replace, while lax parsing
+	// still gets the basics out of files using directives newer than this parser knows about
+	file, err := modfile.Parse(path, content, nil)
+	if err != nil {
+		file, err = modfile.ParseLax(path, content, nil)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return file
+}
+
+// isGoMainFile reports whether path is a non-test Go file of package main that declares func main
+// and is not excluded by build constraints such as //go:build ignore
+func isGoMainFile(path string) bool {
+	if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") || !fileExists(path) {
+		return false
+	}
+
+	if match, err := linuxBuildContext.MatchFile(filepath.Dir(path), filepath.Base(path)); err != nil || !match {
+		return false
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil || file.Name.Name != "main" {
+		return false
+	}
+
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findGoBinaries returns every main package under cmd/, named after its directory. Packages are found
+// the way the go tool does, so cmd/build and cmd/dist count even though source walks skip them.
+func findGoBinaries(dir string) []Binary {
+	var binaries []Binary
+	seen := make(map[string]bool)
+
+	cmdDir := filepath.Join(dir, "cmd")
+	filepath.WalkDir(cmdDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != cmdDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		pkgDir := filepath.Dir(path)
+		if !strings.HasSuffix(path, ".go") || seen[pkgDir] || !isGoMainFile(path) {
+			return nil
+		}
+		seen[pkgDir] = true
+
+		relPath, _ := filepath.Rel(dir, path)
+		binaries = append(binaries, Binary{
+			Name:       filepath.Base(pkgDir),
+			Entrypoint: relPath,
+		})
+		return nil
+	})
+
+	return binaries
+}
+
+// usesCgo reports whether a Go project needs cgo, either because its own code imports "C" or
+// because it depends on a module known to require it
+func usesCgo(dir string) bool {
+	if goMod := readGoMod(dir); goMod != nil {
+		for _, require := range goMod.Require {
+			if isCgoModule(require.Mod.Path) {
+				return true
+			}
+		}
+	}
+
+	// go.sum records a content hash for every module whose code the build downloaded;
+	// "/go.mod" lines only mean the module was part of the dependency graph
+	if content, err := os.ReadFile(filepath.Join(dir, "go.sum")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 3 && !strings.HasSuffix(fields[1], "/go.mod") && isCgoModule(fields[0]) {
+				return true
+			}
+		}
+	}
+
+	found := false
+	walkSourceFiles(dir, []string{".go"}, func(path string) bool {
+		if strings.HasSuffix(path, "_test.go") {
+			return true
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return true
+		}
+
+		for _, imp := range file.Imports {
+			if imp.Path.Value == `"C"` {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+
+	return found
+}
+
+func isCgoModule(modulePath string) bool {
+	for _, module := range cgoModules {
+		if modulePath == module || strings.HasPrefix(modulePath, module+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// findVersionVars finds the build metadata variables of the main packages and of version or buildinfo
+// packages anywhere in the module. -X only sets string variables, so constants and other types are skipped.
+func findVersionVars(dir string, mainFiles []string) []VersionVar {
+	modulePath := ""
+	if modFile := readGoMod(dir); modFile != nil && modFile.Module != nil {
+		modulePath = modFile.Module.Mod.Path
+	}
+
+	var vars []VersionVar
+	seen := make(map[string]bool)
+
+	addPackage := func(pkgDir, importPath string) {
+		entries, err := os.ReadDir(pkgDir)
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			for _, v := range readVersionVars(filepath.Join(pkgDir, entry.Name()), importPath) {
+				if !seen[v.Symbol] {
+					seen[v.Symbol] = true
+					vars = append(vars, v)
+				}
+			}
+		}
+	}
+
+	// Every main package links as main, so its variables are set through main.<name>
+	for _, mainFile := range mainFiles {
+		if mainFile != "" {
+			addPackage(filepath.Dir(filepath.Join(dir, mainFile)), "main")
+		}
+	}
+
+	if modulePath != "" {
+		visited := make(map[string]bool)
+		walkSourceFiles(dir, []string{".go"}, func(path string) bool {
+			pkgDir := filepath.Dir(path)
+			if visited[pkgDir] || !versionPackages[filepath.Base(pkgDir)] {
+				return true
+			}
+			visited[pkgDir] = true
+
+			rel, err := filepath.Rel(dir, pkgDir)
+			if err == nil {
+				addPackage(pkgDir, modulePath+"/"+filepath.ToSlash(rel))
+			}
+			return true
+		})
+	}
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Symbol < vars[j].Symbol })
+	return vars
+}
+
+// readVersionVars returns the build metadata variables declared at the top level of a Go source file
+func readVersionVars(path, importPath string) []VersionVar {
+	if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+		return nil
+	}
+
+	if match, err := linuxBuildContext.MatchFile(filepath.Dir(path), filepath.Base(path)); err != nil || !match {
+		return nil
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil || (importPath == "main") != (file.Name.Name == "main") {
+		return nil
+	}
+
+	var vars []VersionVar
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+
+			if ident, ok := valueSpec.Type.(*ast.Ident); valueSpec.Type != nil && (!ok || ident.Name != "string") {
+				continue
+			}
+
+			for i, name := range valueSpec.Names {
+				arg, ok := versionVarArgs[strings.ToLower(name.Name)]
+				if !ok {
+					continue
+				}
+
+				// -X can only replace an uninitialised variable or one set to a string literal
+				value := ""
+				if len(valueSpec.Values) > 0 {
+					if len(valueSpec.Values) <= i {
+						continue
+					}
+					lit, ok := valueSpec.Values[i].(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					value, _ = strconv.Unquote(lit.Value)
+				} else if valueSpec.Type == nil {
+					continue
+				}
+
+				if !ldflagsSafeValuePattern.MatchString(value) {
+					value = ""
+				}
+
+				vars = append(vars, VersionVar{
+					Symbol:  importPath + "." + name.Name,
+					Arg:     arg,
+					Default: value,
+				})
+			}
+		}
+	}
+
+	return vars
+}
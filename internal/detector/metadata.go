@@ -0,0 +1,261 @@
+package detector
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Metadata describes the project for image labels
+type Metadata struct {
+	Name        string // Go module path or package name
+	Version     string // Version declared in package.json or pyproject.toml
+	Description string
+	License     string // SPDX license expression
+	Source      string // Browsable repository URL
+	Revision    string // Commit checked out in the repository
+}
+
+// pyprojectInlineLicensePattern matches the PEP 621 table form license = { text = "MIT" }
+var pyprojectInlineLicensePattern = regexp.MustCompile(`^license\s*=\s*\{[^}]*\btext\s*=\s*["']([^"']*)["']`)
+
+// scpLikeRemotePattern matches git remotes in the user@host:path form
+var scpLikeRemotePattern = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+):(.+)$`)
+
+// detectMetadata gathers the labels metadata from the manifest of the project type and the git repository
+// containing dir. Projects hosted on a known forge fall back to their module path for the source URL.
+func detectMetadata(dir string, projectType ProjectType) Metadata {
+	var meta Metadata
+
+	switch projectType {
+	case Go:
+		if modFile := readGoMod(dir); modFile != nil && modFile.Module != nil {
+			meta.Name = modFile.Module.Mod.Path
+		}
+	case NodeJS:
+		if pkg := readPackageJSON(dir); pkg != nil {
+			meta.Name = pkg.Name
+			meta.Version = pkg.Version
+			meta.Description = pkg.Description
+			meta.License = pkg.license()
+		}
+	case Python:
+		meta = readPyprojectMetadata(filepath.Join(dir, "pyproject.toml"))
+	}
+
+	meta.Source, meta.Revision = readGitInfo(dir)
+
+	if meta.Source == "" && projectType == Go {
+		parts := strings.SplitN(meta.Name, "/", 4)
+		if len(parts) >= 3 && slices.Contains([]string{"github.com", "gitlab.com", "bitbucket.org"}, parts[0]) {
+			meta.Source = "https://" + strings.Join(parts[:3], "/")
+		}
+	}
+
+	return meta
+}
+
+// license returns the SPDX expression of the license field, which older packages write as {"type": "MIT"}
+func (p *packageJSON) license() string {
+	var license string
+	if json.Unmarshal(p.License, &license) == nil {
+		return license
+	}
+
+	var legacy struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(p.License, &legacy) == nil {
+		return legacy.Type
+	}
+
+	return ""
+}
+
+// readPyprojectMetadata reads the PEP 621 [project] table, falling back to [tool.poetry]
+func readPyprojectMetadata(pyproject string) Metadata {
+	for _, table := range []string{"project", "tool.poetry"} {
+		meta := Metadata{
+			Name:        readTOMLString(pyproject, table, "name"),
+			Version:     readTOMLString(pyproject, table, "version"),
+			Description: readTOMLString(pyproject, table, "description"),
+			License:     readTOMLString(pyproject, table, "license"),
+		}
+
+		if meta.License == "" {
+			meta.License = readTOMLString(pyproject, table+".license", "text")
+		}
+		if meta.License == "" {
+			meta.License = readInlineLicense(pyproject, table)
+		}
+
+		if meta.Name != "" {
+			return meta
+		}
+	}
+
+	return Metadata{}
+}
+
+// readInlineLicense reads license = { text = "..." } from table, which readTOMLString cannot parse
+func readInlineLicense(pyproject, table string) string {
+	content, err := os.ReadFile(pyproject)
+	if err != nil {
+		return ""
+	}
+
+	currentTable := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		if matches := tomlTablePattern.FindStringSubmatch(line); matches != nil {
+			currentTable = matches[1]
+			continue
+		}
+
+		if currentTable != table {
+			continue
+		}
+
+		if matches := pyprojectInlineLicensePattern.FindStringSubmatch(line); matches != nil {
+			return matches[1]
+		}
+	}
+
+	return ""
+}
+
+// readGitInfo returns the browsable URL of the origin remote and the checked out commit of the git
+// repository containing dir, reading .git directly so no git binary is needed
+func readGitInfo(dir string) (source, revision string) {
+	gitDir, commonDir := findGitDir(dir)
+	if gitDir == "" {
+		return "", ""
+	}
+
+	return readOriginURL(filepath.Join(commonDir, "config")), readHead(gitDir, commonDir)
+}
+
+// findGitDir searches dir and its parents for .git. Worktrees and submodules have a .git file pointing
+// at their git directory, which keeps config and refs in the common directory of the main repository.
+func findGitDir(dir string) (gitDir, commonDir string) {
+	for {
+		candidate := filepath.Join(dir, ".git")
+
+		if dirExists(candidate) {
+			return candidate, candidate
+		}
+
+		if data, err := os.ReadFile(candidate); err == nil {
+			target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+			if !ok {
+				return "", ""
+			}
+
+			gitDir = strings.TrimSpace(target)
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+
+			commonDir = gitDir
+			if common := readVersionFile(filepath.Join(gitDir, "commondir")); common != "" {
+				commonDir = common
+				if !filepath.IsAbs(commonDir) {
+					commonDir = filepath.Join(gitDir, commonDir)
+				}
+			}
+
+			return gitDir, commonDir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// readHead resolves HEAD to a commit through loose refs and packed-refs
+func readHead(gitDir, commonDir string) string {
+	head := readVersionFile(filepath.Join(gitDir, "HEAD"))
+
+	ref, ok := strings.CutPrefix(head, "ref:")
+	if !ok {
+		return head
+	}
+	ref = strings.TrimSpace(ref)
+
+	for _, dir := range []string{gitDir, commonDir} {
+		if commit := readVersionFile(filepath.Join(dir, filepath.FromSlash(ref))); commit != "" {
+			return commit
+		}
+	}
+
+	file, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		commit, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return commit
+		}
+	}
+
+	return ""
+}
+
+// readOriginURL reads the url of [remote "origin"] from a git config file
+func readOriginURL(config string) string {
+	content, err := os.ReadFile(config)
+	if err != nil {
+		return ""
+	}
+
+	inOrigin := false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "[") {
+			inOrigin = line == `[remote "origin"]`
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if inOrigin && ok && strings.TrimSpace(key) == "url" {
+			return browsableURL(strings.TrimSpace(value))
+		}
+	}
+
+	return ""
+}
+
+// browsableURL turns a git remote into an https URL without credentials or the .git suffix, e.g.
+// git@github.com:acme/api.git becomes https://github.com/acme/api
+func browsableURL(remote string) string {
+	if !strings.Contains(remote, "://") {
+		matches := scpLikeRemotePattern.FindStringSubmatch(remote)
+		if matches == nil {
+			return ""
+		}
+		remote = "ssh://" + matches[1] + "/" + matches[2]
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	// Only the host and path are kept, so tokens in https remotes never end up in an image
+	return "https://" + u.Hostname() + path.Clean("/"+strings.TrimSuffix(u.Path, ".git"))
+}
@@ -0,0 +1,213 @@
+package detector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	Npm       PackageManager = "npm"
+	Yarn      PackageManager = "yarn"       // Yarn classic (1.x)
+	YarnBerry PackageManager = "yarn-berry" // Yarn 2 and later
+	Pnpm      PackageManager = "pnpm"
+	Bun       PackageManager = "bun"
+)
+
+// DefaultNodeVersion is the Node.js line used when a project does not say which one it needs.
+// It tracks an LTS release so the generated image gets long-term security fixes.
+const DefaultNodeVersion = "22"
+
+// nodeLTSLines are the release lines version ranges are resolved against, highest wins
+var nodeLTSLines = []version{{major: 18}, {major: 20}, {major: 22}, {major: 24}}
+
+var exactNodeVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// packageJSON holds the package.json fields the detector cares about
+type packageJSON struct {
+	Name           string            `json:"name"`
+	Version        string            `json:"version"`
+	Description    string            `json:"description"`
+	License        json.RawMessage   `json:"license"`
+	Main           string            `json:"main"`
+	PackageManager string            `json:"packageManager"`
+	Engines        map[string]string `json:"engines"`
+	Dependencies   map[string]string `json:"dependencies"`
+}
+
+// readPackageJSON parses the package.json in dir, returning nil if it is missing or invalid
+func readPackageJSON(dir string) *packageJSON {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil
+	}
+
+	return &pkg
+}
+
+// detectNodePackageManager works out which package manager a NodeJS project uses and the lockfile it keeps.
+// The packageManager field in package.json wins over lockfiles since it is what corepack enforces.
+func detectNodePackageManager(dir string) (PackageManager, string) {
+	lockFiles := []struct {
+		name    string
+		manager PackageManager
+	}{
+		{"pnpm-lock.yaml", Pnpm},
+		{"bun.lockb", Bun},
+		{"bun.lock", Bun},
+		{"yarn.lock", Yarn},
+		{"package-lock.json", Npm},
+		{"npm-shrinkwrap.json", Npm},
+	}
+
+	var manager PackageManager
+	var lockFile string
+	for _, candidate := range lockFiles {
+		if fileExists(filepath.Join(dir, candidate.name)) {
+			manager, lockFile = candidate.manager, candidate.name
+			break
+		}
+	}
+
+	if pkg := readPackageJSON(dir); pkg != nil && pkg.PackageManager != "" {
+		if declared := parsePackageManagerField(pkg.PackageManager); declared != "" {
+			manager = declared
+			if !lockFileMatches(lockFile, manager) {
+				lockFile = ""
+			}
+		}
+	}
+
+	if manager == Yarn && isYarnBerry(dir) {
+		manager = YarnBerry
+	}
+
+	if manager == "" {
+		manager = Npm
+	}
+
+	return manager, lockFile
+}
+
+// parsePackageManagerField reads corepack's "name@version" packageManager value
+func parsePackageManagerField(value string) PackageManager {
+	name, version, _ := strings.Cut(value, "@")
+
+	switch name {
+	case "npm":
+		return Npm
+	case "pnpm":
+		return Pnpm
+	case "bun":
+		return Bun
+	case "yarn":
+		major, _, _ := strings.Cut(version, ".")
+		if n, err := strconv.Atoi(major); err == nil && n >= 2 {
+			return YarnBerry
+		}
+		return Yarn
+	default:
+		return ""
+	}
+}
+
+// lockFileMatches reports whether lockFile belongs to manager
+func lockFileMatches(lockFile string, manager PackageManager) bool {
+	switch lockFile {
+	case "pnpm-lock.yaml":
+		return manager == Pnpm
+	case "bun.lockb", "bun.lock":
+		return manager == Bun
+	case "yarn.lock":
+		return manager == Yarn || manager == YarnBerry
+	case "package-lock.json", "npm-shrinkwrap.json":
+		return manager == Npm
+	default:
+		return false
+	}
+}
+
+// isYarnBerry tells Yarn 2+ projects apart from Yarn classic ones
+func isYarnBerry(dir string) bool {
+	if fileExists(filepath.Join(dir, ".yarnrc.yml")) {
+		return true
+	}
+
+	// Berry lockfiles are YAML and start with a __metadata block
+	content, err := os.ReadFile(filepath.Join(dir, "yarn.lock"))
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(content), "__metadata:")
+}
+
+// detectNodeVersion resolves the Node.js version from .nvmrc, .node-version, .tool-versions and
+// finally the engines.node range in package.json, falling back to DefaultNodeVersion
+func detectNodeVersion(dir string) string {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		if v := normalizeNodeVersion(readVersionFile(filepath.Join(dir, name))); v != "" {
+			return v
+		}
+	}
+
+	if v := normalizeNodeVersion(readToolVersion(dir, "nodejs", "node")); v != "" {
+		return v
+	}
+
+	if pkg := readPackageJSON(dir); pkg != nil {
+		if v := resolveNodeRange(pkg.Engines["node"]); v != "" {
+			return v
+		}
+	}
+
+	return DefaultNodeVersion
+}
+
+// normalizeNodeVersion turns a version manager value into a node image tag, e.g. "v20.11.1" into
+// "20.11.1" and "lts/iron" into "iron"
+func normalizeNodeVersion(value string) string {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+
+	switch {
+	case value == "":
+		return ""
+	case value == "lts/*":
+		return "lts"
+	case strings.HasPrefix(value, "lts/"):
+		return strings.ToLower(strings.TrimPrefix(value, "lts/"))
+	case value == "node" || value == "stable" || value == "latest" || value == "current":
+		return "current"
+	case exactNodeVersionPattern.MatchString(value):
+		return value
+	default:
+		return resolveNodeRange(value)
+	}
+}
+
+// resolveNodeRange picks the highest LTS major allowed by a semver range such as ">=18" or "^20.10".
+// Ranges that only allow older lines resolve to their lowest major.
+func resolveNodeRange(constraint string) string {
+	ranges, ok := parseVersionConstraint(constraint)
+	if !ok {
+		return ""
+	}
+
+	if line, found := highestMatchingLine(ranges, nodeLTSLines, 1); found {
+		return strconv.Itoa(line.major)
+	}
+
+	if ranges[0].lower.major > 0 {
+		return strconv.Itoa(ranges[0].lower.major)
+	}
+
+	return ""
+}
@@ -0,0 +1,166 @@
+package detector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultPorts are used when neither environment files nor source code reveal a port
+var defaultPorts = map[ProjectType]int{
+	Go:     8080,
+	NodeJS: 3000,
+	Python: 8000,
+}
+
+// portPatterns match listen calls and PORT defaults in source code; the first group is the port
+var portPatterns = map[ProjectType][]*regexp.Regexp{
+	Go: {
+		regexp.MustCompile(`ListenAndServe(?:TLS)?\(\s*"[\w.\-]*:(\d+)"`),
+		regexp.MustCompile(`\.(?:Run|RunTLS|Start|StartTLS|Listen|ListenTLS)\(\s*"[\w.\-]*:(\d+)"`),
+		regexp.MustCompile(`net\.Listen\(\s*"tcp[46]?"\s*,\s*"[\w.\-]*:(\d+)"`),
+		regexp.MustCompile(`Addr:\s*"[\w.\-]*:(\d+)"`),
+		regexp.MustCompile(`cmp\.Or\(\s*os\.Getenv\("PORT"\)\s*,\s*"(\d+)"`),
+		regexp.MustCompile(`\w*[Ee]nv\w*\(\s*"PORT"\s*,\s*"(\d+)"`),
+		regexp.MustCompile("(?:env|envconfig):\"PORT\"[^`]*?(?:envDefault|default):\"(\\d+)\""),
+	},
+	NodeJS: {
+		regexp.MustCompile(`\.listen\(\s*(\d+)`),
+		regexp.MustCompile(`\.listen\(\s*\{[^}]*\bport:\s*(\d+)`),
+		regexp.MustCompile(`process\.env\.PORT\s*(?:\|\||\?\?)\s*['"]?(\d+)`),
+		regexp.MustCompile(`(?i)\b(?:const|let|var)\s+port\s*=\s*['"]?(\d+)`),
+	},
+	Python: {
+		regexp.MustCompile(`uvicorn\.run\([^)]*\bport\s*=\s*(\d+)`),
+		regexp.MustCompile(`\.run\([^)]*\bport\s*=\s*(\d+)`),
+		regexp.MustCompile(`os\.environ\.get\(\s*['"]PORT['"]\s*,\s*['"]?(\d+)`),
+		regexp.MustCompile(`os\.getenv\(\s*['"]PORT['"]\s*,\s*['"]?(\d+)`),
+		regexp.MustCompile(`\bbind\s*=\s*['"][\w.\-]*:(\d+)`),
+	},
+}
+
+// portEnvPattern matches the PORT key of an environment file, but not keys ending in PORT such as DB_PORT
+var portEnvPattern = regexp.MustCompile(`(?m)^\s*(?:export\s+)?PORT\s*=\s*["']?(\d+)`)
+
+// goGetenvPortPattern finds `port := os.Getenv("PORT")` so the fallback assigned to it can be looked up
+var goGetenvPortPattern = regexp.MustCompile(`(\w+)\s*:?=\s*os\.Getenv\("PORT"\)`)
+
+// sourceExtensions lists the file extensions scanned for each project type
+var sourceExtensions = map[ProjectType][]string{
+	Go:     {".go"},
+	NodeJS: {".js", ".mjs", ".cjs", ".ts", ".mts", ".cts"},
+	Python: {".py"},
+}
+
+// detectPort tries to find the port number used in the project and reports where it came from.
+// The PORT variable in environment files wins, then listen calls and PORT defaults in the source
+// (starting with the entrypoint), and finally the usual default port for the project type.
+func detectPort(dir string, projectType ProjectType, entrypoint string) (int, string) {
+
+	// First check environment files
+	envFiles := []string{
+		filepath.Join(dir, ".env"),
+		filepath.Join(dir, ".env.development"),
+		filepath.Join(dir, ".env.local"),
+	}
+
+	for _, envFile := range envFiles {
+		if fileExists(envFile) {
+			content, err := os.ReadFile(envFile)
+			if err == nil {
+				matches := portEnvPattern.FindStringSubmatch(string(content))
+				if len(matches) > 1 {
+					port, _ := strconv.Atoi(matches[1])
+					return port, filepath.Base(envFile)
+				}
+			}
+		}
+	}
+
+	// Then look through the source, entrypoint first
+	if entrypoint != "" {
+		if port, line := findPortInFile(filepath.Join(dir, entrypoint), projectType); port != 0 {
+			return port, fmt.Sprintf("%s:%d", filepath.ToSlash(entrypoint), line)
+		}
+	}
+
+	var port int
+	var source string
+	walkSourceFiles(dir, sourceExtensions[projectType], func(path string) bool {
+		found, line := findPortInFile(path, projectType)
+		if found == 0 {
+			return true
+		}
+
+		relPath, _ := filepath.Rel(dir, path)
+		port, source = found, fmt.Sprintf("%s:%d", filepath.ToSlash(relPath), line)
+		return false
+	})
+
+	if port != 0 {
+		return port, source
+	}
+
+	if port, ok := defaultPorts[projectType]; ok {
+		return port, "default"
+	}
+
+	return 3000, "default"
+}
+
+// findPortInFile returns the first port found in a source file and the line it is on, or zero
+func findPortInFile(path string, projectType ProjectType) (int, int) {
+	if strings.HasSuffix(path, "_test.go") {
+		return 0, 0
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+	content := string(data)
+
+	// Pick the earliest match across all patterns so the result follows the order of the code
+	bestIndex := -1
+	bestPort := 0
+	consider := func(index int, value string) {
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return
+		}
+		if bestIndex == -1 || index < bestIndex {
+			bestIndex, bestPort = index, port
+		}
+	}
+
+	for _, pattern := range portPatterns[projectType] {
+		if loc := pattern.FindStringSubmatchIndex(content); loc != nil {
+			consider(loc[2], content[loc[2]:loc[3]])
+		}
+	}
+
+	// port := os.Getenv("PORT"); if port == "" { port = "8080" }
+	if projectType == Go {
+		for _, loc := range goGetenvPortPattern.FindAllStringSubmatchIndex(content, -1) {
+			name := content[loc[2]:loc[3]]
+			fallback := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\s*=\s*"(\d+)"`)
+
+			rest := content[loc[1]:]
+			if len(rest) > 300 {
+				rest = rest[:300]
+			}
+			if match := fallback.FindStringSubmatchIndex(rest); match != nil {
+				consider(loc[1]+match[2], rest[match[2]:match[3]])
+			}
+		}
+	}
+
+	if bestIndex == -1 {
+		return 0, 0
+	}
+
+	return bestPort, strings.Count(content[:bestIndex], "\n") + 1
+}
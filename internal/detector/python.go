@@ -0,0 +1,199 @@
+package detector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	Pip    PackageManager = "pip"
+	Poetry PackageManager = "poetry"
+	Pipenv PackageManager = "pipenv"
+	Uv     PackageManager = "uv"
+	PDM    PackageManager = "pdm"
+)
+
+// DefaultPythonVersion is the Python minor used when a project does not say which one it needs
+const DefaultPythonVersion = "3.12"
+
+// pythonLines are the minor releases version ranges are resolved against, highest wins
+var pythonLines = []version{
+	{major: 3, minor: 9},
+	{major: 3, minor: 10},
+	{major: 3, minor: 11},
+	{major: 3, minor: 12},
+	{major: 3, minor: 13},
+	{major: 3, minor: 14},
+}
+
+var (
+	runtimeTxtPattern = regexp.MustCompile(`^python-(\d+\.\d+(\.\d+)?)`)
+	tomlTablePattern  = regexp.MustCompile(`^\[+\s*([^\]]+?)\s*\]+$`)
+)
+
+// detectPythonVersion resolves the interpreter version as "X.Y" from .python-version, .tool-versions,
+// runtime.txt, pyproject.toml and Pipfile, falling back to DefaultPythonVersion
+func detectPythonVersion(dir string) string {
+	pyproject := filepath.Join(dir, "pyproject.toml")
+	pipfile := filepath.Join(dir, "Pipfile")
+
+	sources := []string{
+		readVersionFile(filepath.Join(dir, ".python-version")),
+		readToolVersion(dir, "python"),
+		readRuntimeTxtVersion(dir),
+		readTOMLString(pyproject, "project", "requires-python"),
+		readTOMLString(pyproject, "tool.poetry.dependencies", "python"),
+		readTOMLString(pipfile, "requires", "python_full_version"),
+		readTOMLString(pipfile, "requires", "python_version"),
+	}
+
+	for _, source := range sources {
+		if v := resolvePythonVersion(source); v != "" {
+			return v
+		}
+	}
+
+	return DefaultPythonVersion
+}
+
+// readRuntimeTxtVersion reads the "python-3.11.4" style version from a Heroku runtime.txt
+func readRuntimeTxtVersion(dir string) string {
+	matches := runtimeTxtPattern.FindStringSubmatch(readVersionFile(filepath.Join(dir, "runtime.txt")))
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+// resolvePythonVersion turns an exact version or a range like ">=3.9,<3.13" into the highest
+// compatible "X.Y" minor. Ranges that only allow older releases resolve to their lowest minor.
+func resolvePythonVersion(constraint string) string {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || !strings.ContainsAny(constraint, "0123456789") {
+		return ""
+	}
+
+	ranges, ok := parseVersionConstraint(constraint)
+	if !ok {
+		return ""
+	}
+
+	if line, found := highestMatchingLine(ranges, pythonLines, 2); found {
+		return fmt.Sprintf("%d.%d", line.major, line.minor)
+	}
+
+	if lower := ranges[0].lower; lower.major > 0 {
+		return fmt.Sprintf("%d.%d", lower.major, lower.minor)
+	}
+
+	return ""
+}
+
+// detectPythonPackageManager works out which tool manages a Python project's dependencies and the
+// lockfile it keeps. Lockfiles are checked first, then the pyproject.toml build backend and tool tables.
+func detectPythonPackageManager(dir string) (PackageManager, string) {
+	lockFiles := []struct {
+		name    string
+		manager PackageManager
+	}{
+		{"uv.lock", Uv},
+		{"poetry.lock", Poetry},
+		{"pdm.lock", PDM},
+		{"Pipfile.lock", Pipenv},
+	}
+
+	for _, candidate := range lockFiles {
+		if fileExists(filepath.Join(dir, candidate.name)) {
+			return candidate.manager, candidate.name
+		}
+	}
+
+	pyproject := filepath.Join(dir, "pyproject.toml")
+
+	backend := readTOMLString(pyproject, "build-system", "build-backend")
+	switch {
+	case strings.HasPrefix(backend, "poetry."):
+		return Poetry, ""
+	case strings.HasPrefix(backend, "pdm."):
+		return PDM, ""
+	}
+
+	tables := []struct {
+		name    string
+		manager PackageManager
+	}{
+		{"tool.poetry", Poetry},
+		{"tool.uv", Uv},
+		{"tool.pdm", PDM},
+	}
+
+	for _, table := range tables {
+		if hasTOMLTable(pyproject, table.name) {
+			return table.manager, ""
+		}
+	}
+
+	if fileExists(filepath.Join(dir, "Pipfile")) {
+		return Pipenv, ""
+	}
+
+	if fileExists(filepath.Join(dir, "requirements.txt")) {
+		return Pip, "requirements.txt"
+	}
+
+	return Pip, ""
+}
+
+// readTOMLString returns a string value from a TOML table. It understands just enough TOML for the
+// flat key = "value" pairs found in pyproject.toml and Pipfile.
+func readTOMLString(path, table, key string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	keyPattern := regexp.MustCompile(`^"?` + regexp.QuoteMeta(key) + `"?\s*=\s*["']([^"']*)["']`)
+
+	currentTable := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if matches := tomlTablePattern.FindStringSubmatch(line); matches != nil {
+			currentTable = matches[1]
+			continue
+		}
+
+		if currentTable != table {
+			continue
+		}
+
+		if matches := keyPattern.FindStringSubmatch(line); matches != nil {
+			return matches[1]
+		}
+	}
+
+	return ""
+}
+
+// hasTOMLTable reports whether a TOML file defines the table or one of its sub-tables
+func hasTOMLTable(path, table string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		matches := tomlTablePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if matches != nil && (matches[1] == table || strings.HasPrefix(matches[1], table+".")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+package detector
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// version is a major.minor.patch triple; missing components are zero
+type version struct {
+	major, minor, patch int
+}
+
+func (v version) less(other version) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.patch < other.patch
+}
+
+// bump returns the first version after every version matching the first precision components of v,
+// e.g. bumping 1.2 at precision 2 gives 1.3.0
+func (v version) bump(precision int) version {
+	switch precision {
+	case 1:
+		return version{major: v.major + 1}
+	case 2:
+		return version{major: v.major, minor: v.minor + 1}
+	default:
+		return version{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+}
+
+// versionRange is the half-open interval [lower, upper) of versions allowed by a constraint
+type versionRange struct {
+	lower     version
+	upper     version
+	unbounded bool // true when there is no upper limit
+}
+
+func (r versionRange) intersect(other versionRange) versionRange {
+	result := r
+	if result.lower.less(other.lower) {
+		result.lower = other.lower
+	}
+	if !other.unbounded && (result.unbounded || other.upper.less(result.upper)) {
+		result.upper = other.upper
+		result.unbounded = false
+	}
+	return result
+}
+
+// overlaps reports whether any version in [start, end) is inside the range
+func (r versionRange) overlaps(start, end version) bool {
+	return r.lower.less(end) && (r.unbounded || start.less(r.upper))
+}
+
+var (
+	operatorSpacePattern = regexp.MustCompile(`(===|==|!=|~=|>=|<=|[<>=^~])\s+`)
+	comparatorPattern    = regexp.MustCompile(`^(===|==|!=|~=|>=|<=|[<>=^~])?v?(.*)$`)
+)
+
+// parseVersionConstraint parses npm semver, PEP 440 and Poetry style constraints such as "^20",
+// ">=18 <21", "18 - 20", "20.x", ">=3.9,<3.13" or "~=3.10". Alternatives separated by "||" each
+// produce a range. The boolean is false when nothing in the expression could be understood.
+func parseVersionConstraint(expr string) ([]versionRange, bool) {
+	var ranges []versionRange
+
+	for _, alternative := range strings.Split(expr, "||") {
+		alternative = strings.TrimSpace(strings.ReplaceAll(alternative, ",", " "))
+		if alternative == "" {
+			continue
+		}
+
+		// Hyphen ranges are inclusive on both ends: "18 - 20" allows every 20.x release
+		if from, to, found := strings.Cut(alternative, " - "); found {
+			lower, _, okLower := parsePartialVersion(strings.TrimSpace(from))
+			upper, precision, okUpper := parsePartialVersion(strings.TrimSpace(to))
+			if okLower && okUpper {
+				ranges = append(ranges, versionRange{lower: lower, upper: upper.bump(precision)})
+			}
+			continue
+		}
+
+		alternative = operatorSpacePattern.ReplaceAllString(alternative, "$1")
+
+		current := versionRange{unbounded: true}
+		understood := false
+		for _, comparator := range strings.Fields(alternative) {
+			r, ok := parseComparator(comparator)
+			if !ok {
+				continue
+			}
+			current = current.intersect(r)
+			understood = true
+		}
+
+		if understood {
+			ranges = append(ranges, current)
+		}
+	}
+
+	return ranges, len(ranges) > 0
+}
+
+// parseComparator converts a single comparator like ">=18.2" into the range it allows
+func parseComparator(comparator string) (versionRange, bool) {
+	matches := comparatorPattern.FindStringSubmatch(comparator)
+	if matches == nil {
+		return versionRange{}, false
+	}
+
+	operator := matches[1]
+	v, precision, ok := parsePartialVersion(matches[2])
+	if !ok {
+		return versionRange{}, false
+	}
+
+	// A bare wildcard such as "*" or "x" allows everything
+	if precision == 0 {
+		return versionRange{unbounded: true}, true
+	}
+
+	switch operator {
+	case "", "=", "==", "===":
+		return versionRange{lower: v, upper: v.bump(precision)}, true
+	case ">=":
+		return versionRange{lower: v, unbounded: true}, true
+	case ">":
+		return versionRange{lower: v.bump(precision), unbounded: true}, true
+	case "<":
+		return versionRange{upper: v}, true
+	case "<=":
+		return versionRange{upper: v.bump(precision)}, true
+	case "^":
+		// Caret allows changes that do not modify the left-most non-zero component
+		switch {
+		case v.major > 0 || precision == 1:
+			return versionRange{lower: v, upper: v.bump(1)}, true
+		case v.minor > 0 || precision == 2:
+			return versionRange{lower: v, upper: v.bump(2)}, true
+		default:
+			return versionRange{lower: v, upper: v.bump(3)}, true
+		}
+	case "~":
+		if precision == 1 {
+			return versionRange{lower: v, upper: v.bump(1)}, true
+		}
+		return versionRange{lower: v, upper: v.bump(2)}, true
+	case "~=":
+		// PEP 440 compatible release: ~=3.10 means >=3.10,<4 and ~=3.10.2 means >=3.10.2,<3.11
+		if precision < 2 {
+			return versionRange{}, false
+		}
+		return versionRange{lower: v, upper: v.bump(precision - 1)}, true
+	case "!=":
+		// Exclusions never change which release line is picked
+		return versionRange{unbounded: true}, true
+	default:
+		return versionRange{}, false
+	}
+}
+
+// parsePartialVersion parses versions such as "20", "3.11.*", "1.2.x" or "3.12.1rc1". The precision is
+// the number of components given before the first wildcard.
+func parsePartialVersion(s string) (version, int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return version{}, 0, false
+	}
+
+	var components [3]int
+	precision := 0
+	for _, part := range strings.SplitN(s, ".", 3) {
+		if part == "*" || part == "x" || part == "X" {
+			break
+		}
+
+		// Drop pre-release and build suffixes like "1rc1" or "0-beta"
+		digits := part
+		if end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			digits = part[:end]
+		}
+
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			if precision == 0 {
+				return version{}, 0, false
+			}
+			break
+		}
+
+		components[precision] = n
+		precision++
+		if digits != part {
+			break
+		}
+	}
+
+	return version{major: components[0], minor: components[1], patch: components[2]}, precision, true
+}
+
+// highestMatchingLine returns the highest candidate release line that overlaps any of the ranges.
+// precision selects what a line is: 1 for a major line like Node.js 20, 2 for a minor line like Python 3.12.
+func highestMatchingLine(ranges []versionRange, candidates []version, precision int) (version, bool) {
+	var best version
+	found := false
+
+	for _, candidate := range candidates {
+		for _, r := range ranges {
+			if r.overlaps(candidate, candidate.bump(precision)) && (!found || best.less(candidate)) {
+				best = candidate
+				found = true
+			}
+		}
+	}
+
+	return best, found
+}
@@ -0,0 +1,269 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// BackingService is a database, cache or message broker the project connects to
+type BackingService string
+
+const (
+	Postgres BackingService = "postgres"
+	MySQL    BackingService = "mysql"
+	Redis    BackingService = "redis"
+	MongoDB  BackingService = "mongodb"
+	RabbitMQ BackingService = "rabbitmq"
+	Kafka    BackingService = "kafka"
+)
+
+// backingServices is the order services are reported in
+var backingServices = []BackingService{Postgres, MySQL, Redis, MongoDB, RabbitMQ, Kafka}
+
+// goServiceModules maps module paths to the service their driver talks to. Major version suffixes such
+// as /v5 match through the path prefix.
+var goServiceModules = map[string]BackingService{
+	"github.com/lib/pq":                          Postgres,
+	"github.com/jackc/pgx":                       Postgres,
+	"github.com/go-pg/pg":                        Postgres,
+	"gorm.io/driver/postgres":                    Postgres,
+	"github.com/uptrace/bun/driver/pgdriver":     Postgres,
+	"github.com/go-sql-driver/mysql":             MySQL,
+	"gorm.io/driver/mysql":                       MySQL,
+	"github.com/redis/go-redis":                  Redis,
+	"github.com/go-redis/redis":                  Redis,
+	"github.com/gomodule/redigo":                 Redis,
+	"github.com/redis/rueidis":                   Redis,
+	"go.mongodb.org/mongo-driver":                MongoDB,
+	"github.com/rabbitmq/amqp091-go":             RabbitMQ,
+	"github.com/streadway/amqp":                  RabbitMQ,
+	"github.com/segmentio/kafka-go":              Kafka,
+	"github.com/IBM/sarama":                      Kafka,
+	"github.com/Shopify/sarama":                  Kafka,
+	"github.com/confluentinc/confluent-kafka-go": Kafka,
+	"github.com/twmb/franz-go":                   Kafka,
+}
+
+// nodeServicePackages maps npm packages to the service they talk to
+var nodeServicePackages = map[string]BackingService{
+	"pg":                      Postgres,
+	"postgres":                Postgres,
+	"pg-promise":              Postgres,
+	"mysql":                   MySQL,
+	"mysql2":                  MySQL,
+	"redis":                   Redis,
+	"ioredis":                 Redis,
+	"mongodb":                 MongoDB,
+	"mongoose":                MongoDB,
+	"amqplib":                 RabbitMQ,
+	"amqp-connection-manager": RabbitMQ,
+	"kafkajs":                 Kafka,
+	"node-rdkafka":            Kafka,
+}
+
+// pythonServicePackages maps normalized PyPI names to the service they talk to
+var pythonServicePackages = map[string]BackingService{
+	"psycopg":                Postgres,
+	"psycopg2":               Postgres,
+	"psycopg2-binary":        Postgres,
+	"asyncpg":                Postgres,
+	"pg8000":                 Postgres,
+	"pymysql":                MySQL,
+	"mysqlclient":            MySQL,
+	"mysql-connector-python": MySQL,
+	"aiomysql":               MySQL,
+	"redis":                  Redis,
+	"aioredis":               Redis,
+	"pymongo":                MongoDB,
+	"motor":                  MongoDB,
+	"mongoengine":            MongoDB,
+	"pika":                   RabbitMQ,
+	"aio-pika":               RabbitMQ,
+	"kafka-python":           Kafka,
+	"confluent-kafka":        Kafka,
+	"aiokafka":               Kafka,
+}
+
+// connectionURLSchemes maps the schemes of connection URLs, including SQLAlchemy's dialect+driver form,
+// to their service
+var connectionURLSchemes = map[string]BackingService{
+	"postgres":   Postgres,
+	"postgresql": Postgres,
+	"mysql":      MySQL,
+	"mariadb":    MySQL,
+	"redis":      Redis,
+	"rediss":     Redis,
+	"mongodb":    MongoDB,
+	"amqp":       RabbitMQ,
+	"amqps":      RabbitMQ,
+}
+
+var (
+	connectionURLPattern         = regexp.MustCompile(`\b([a-z]+)(?:\+\w+)?://`)
+	pythonRequirementPattern     = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)`)
+	pythonDependencyKeyPattern   = regexp.MustCompile(`^"?([A-Za-z0-9][A-Za-z0-9._-]*)"?\s*=`)
+	pyprojectDependenciesPattern = regexp.MustCompile(`^dependencies\s*=\s*`)
+	quotedStringPattern          = regexp.MustCompile(`["']([^"']+)["']`)
+)
+
+// detectServices finds the backing services the project depends on from its dependency manifests.
+// Python projects are also searched for connection URLs, which is how SQLAlchemy selects a database.
+func detectServices(dir string, projectType ProjectType) []BackingService {
+	found := map[BackingService]bool{}
+
+	switch projectType {
+	case Go:
+		if goMod := readGoMod(dir); goMod != nil {
+			for _, require := range goMod.Require {
+				// Indirect requirements are pulled in by libraries the project may never use them through
+				if require.Indirect {
+					continue
+				}
+				if service, ok := goServiceModule(require.Mod.Path); ok {
+					found[service] = true
+				}
+			}
+		}
+	case NodeJS:
+		if pkg := readPackageJSON(dir); pkg != nil {
+			for name := range pkg.Dependencies {
+				if service, ok := nodeServicePackages[name]; ok {
+					found[service] = true
+				}
+			}
+		}
+	case Python:
+		for _, name := range readPythonDependencies(dir) {
+			if service, ok := pythonServicePackages[name]; ok {
+				found[service] = true
+			}
+		}
+
+		files := []string{filepath.Join(dir, ".env")}
+		walkSourceFiles(dir, sourceExtensions[Python], func(path string) bool {
+			files = append(files, path)
+			return true
+		})
+		for _, file := range files {
+			for _, service := range findConnectionURLs(file) {
+				found[service] = true
+			}
+		}
+	}
+
+	var services []BackingService
+	for _, service := range backingServices {
+		if found[service] {
+			services = append(services, service)
+		}
+	}
+
+	return services
+}
+
+func goServiceModule(modulePath string) (BackingService, bool) {
+	for module, service := range goServiceModules {
+		if modulePath == module || strings.HasPrefix(modulePath, module+"/") {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+// findConnectionURLs returns the services of the connection URLs written in a file
+func findConnectionURLs(path string) []BackingService {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var services []BackingService
+	for _, matches := range connectionURLPattern.FindAllStringSubmatch(string(content), -1) {
+		if service, ok := connectionURLSchemes[matches[1]]; ok && !slices.Contains(services, service) {
+			services = append(services, service)
+		}
+	}
+
+	return services
+}
+
+// readPythonDependencies returns the normalized names of the packages listed in requirements.txt, the
+// dependencies array of [project], [tool.poetry.dependencies] and the [packages] of Pipfile
+func readPythonDependencies(dir string) []string {
+	var names []string
+
+	if content, err := os.ReadFile(filepath.Join(dir, "requirements.txt")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if matches := pythonRequirementPattern.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+				names = append(names, normalizePythonName(matches[1]))
+			}
+		}
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "pyproject.toml")); err == nil {
+		currentTable := ""
+		inDependencies := false
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+
+			if matches := tomlTablePattern.FindStringSubmatch(line); matches != nil {
+				currentTable = matches[1]
+				inDependencies = false
+				continue
+			}
+
+			switch {
+			case currentTable == "project" && pyprojectDependenciesPattern.MatchString(line):
+				inDependencies = true
+				line = pyprojectDependenciesPattern.ReplaceAllString(line, "")
+			case currentTable == "tool.poetry.dependencies":
+				if matches := pythonDependencyKeyPattern.FindStringSubmatch(line); matches != nil {
+					names = append(names, normalizePythonName(matches[1]))
+				}
+				continue
+			}
+
+			if !inDependencies {
+				continue
+			}
+
+			for _, requirement := range quotedStringPattern.FindAllStringSubmatch(line, -1) {
+				if matches := pythonRequirementPattern.FindStringSubmatch(strings.TrimSpace(requirement[1])); matches != nil {
+					names = append(names, normalizePythonName(matches[1]))
+				}
+			}
+
+			// Extras such as celery[redis] are inside the quotes, so a bracket outside them closes the array
+			if strings.Contains(quotedStringPattern.ReplaceAllString(line, ""), "]") {
+				inDependencies = false
+			}
+		}
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "Pipfile")); err == nil {
+		currentTable := ""
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+
+			if matches := tomlTablePattern.FindStringSubmatch(line); matches != nil {
+				currentTable = matches[1]
+				continue
+			}
+
+			if matches := pythonDependencyKeyPattern.FindStringSubmatch(line); currentTable == "packages" && matches != nil {
+				names = append(names, normalizePythonName(matches[1]))
+			}
+		}
+	}
+
+	return names
+}
+
+// normalizePythonName applies the PEP 503 normalization, so PyMySQL and psycopg2_binary match the table
+func normalizePythonName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "-", ".", "-").Replace(name)
+}
@@ -3,299 +3,463 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+	"gopkg.in/yaml.v3"
 )
 
-// GenerateDockerCompose creates a default docker-compose.yml file
-func GenerateDockerCompose(projectName, port string) (string, error) {
+// composeProjectNamePattern matches the project names Docker Compose accepts
+var composeProjectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// GenerateDockerCompose creates a default docker-compose.yml file. Projects with several binaries get a
+// service per binary, built from its target stage or, with opts.PerBinary, from its own Dockerfile.<name>.
+// Only the primary service publishes ports, so workers sharing the image do not compete for host ports.
+func GenerateDockerCompose(projectName string, project *detector.Project, opts Options) (string, error) {
+
+	if projectName == "" {
+		return "", fmt.Errorf("project name is required")
+	}
+
+	if !composeProjectNamePattern.MatchString(projectName) {
+		return "", fmt.Errorf("invalid project name %q: use lowercase letters, digits, dashes and underscores, starting with a letter or digit", projectName)
+	}
+
+	if project == nil {
+		return "", fmt.Errorf("project cannot be nil")
+	}
+
+	ports, err := composePorts(project.Port, opts.Publish)
+	if err != nil {
+		return "", err
+	}
+
+	context, dockerfileDir := buildContext(project)
+
+	var services []Service
+	if len(project.Binaries) > 1 {
+		primary := primaryBinary(project)
+		for _, b := range project.Binaries {
+			build := Build{
+				Context:    context,
+				Dockerfile: path.Join(dockerfileDir, "Dockerfile"),
+				Target:     b.Name,
+			}
+			if opts.PerBinary {
+				build = Build{
+					Context:    context,
+					Dockerfile: path.Join(dockerfileDir, fmt.Sprintf("Dockerfile.%s", b.Name)),
+				}
+			}
+
+			service := appService(b.Name, fmt.Sprintf("%s-%s", projectName, b.Name), build)
+			if b.Name == primary {
+				service.Ports = ports
+			}
+			services = append(services, service)
+		}
+	} else {
+		service := appService("app", fmt.Sprintf("%s-app", projectName), Build{
+			Context:    context,
+			Dockerfile: path.Join(dockerfileDir, "Dockerfile"),
+		})
+		service.Ports = ports
+		services = append(services, service)
+	}
 
-	if projectName == "" || port == "" {
-		return "", fmt.Errorf("project name and port are required")
+	for i := range services {
+		environment, passThrough := appEnvironment(project)
+		for key, value := range environment {
+			services[i].Environment[key] = value
+		}
+		services[i].PassEnvironment = passThrough
 	}
 
 	composeTemplate := DockerComposeTemplate{
-		Version: "3.8",
-		Name:    projectName,
-		Services: []Service{
-			{
-				Name:          "app",
-				ContainerName: fmt.Sprintf("%s-app", projectName),
-				Restart:       "unless-stopped",
-				Build: Build{
-					Context:    ".",
-					Dockerfile: "Dockerfile",
-				},
-				Environment: map[string]string{
-					"ENV": "development",
-				},
-			},
-		},
+		Name:     projectName,
+		Services: services,
+	}
+
+	if len(opts.Platforms) > 0 {
+		for i := range composeTemplate.Services {
+			composeTemplate.Services[i].Build.Platforms = opts.Platforms
+		}
+	}
+
+	// Give the builds the same credentials the Dockerfile's go mod download mounts
+	if project.Type == detector.Go && !project.Vendored {
+		switch opts.PrivateModules {
+		case PrivateModulesSSH:
+			for i := range composeTemplate.Services {
+				composeTemplate.Services[i].Build.SSH = []string{"default"}
+			}
+		case PrivateModulesNetrc:
+			for i := range composeTemplate.Services {
+				composeTemplate.Services[i].Build.Secrets = []string{"netrc"}
+			}
+			composeTemplate.Secrets = append(composeTemplate.Secrets, Secret{Name: "netrc", File: "${HOME}/.netrc"})
+		}
+	}
+
+	// Backing services run published images, so they come after the build options of the app services
+	addBackingServices(&composeTemplate, projectName, project.Services)
+
+	if err := applyComposeSpec(&composeTemplate, opts.ComposeSpec); err != nil {
+		return "", err
 	}
 
 	return renderDockerCompose(composeTemplate)
 }
 
-// renderDockerCompose converts the template to YAML content
-func renderDockerCompose(template DockerComposeTemplate) (string, error) {
-	var buf bytes.Buffer
+// applyComposeSpec adapts the template to a file format. The Compose Specification supports every field;
+// the legacy formats need a version key and lose what their docker-compose releases would reject.
+func applyComposeSpec(template *DockerComposeTemplate, spec string) error {
+	switch spec {
+	case "", ComposeSpecCompose:
+		template.Version = ""
+		return nil
+	case ComposeSpecV2:
+		template.Version = "2.4"
+	case ComposeSpecV3:
+		template.Version = "3.8"
+	default:
+		return fmt.Errorf("unsupported compose spec %q, use %s, %s or %s", spec, ComposeSpecCompose, ComposeSpecV2, ComposeSpecV3)
+	}
 
-	// Helper to write quoted strings if necessary
-	quote := func(s string) string {
-		if strings.ContainsAny(s, ": \t\"'") {
-			return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+	// The name key, build platforms and build-time ssh and secrets came with the Compose Specification
+	template.Name = ""
+	buildSecrets := map[string]bool{}
+	for i := range template.Services {
+		build := &template.Services[i].Build
+		for _, secret := range build.Secrets {
+			buildSecrets[secret] = true
 		}
-		return s
+		build.Platforms = nil
+		build.SSH = nil
+		build.Secrets = nil
 	}
 
-	// Write version
-	buf.WriteString(fmt.Sprintf("version: '%s'\n\n", template.Version))
+	var secrets []Secret
+	for _, secret := range template.Secrets {
+		if !buildSecrets[secret.Name] {
+			secrets = append(secrets, secret)
+		}
+	}
+	template.Secrets = secrets
 
-	// Write name
-	buf.WriteString(fmt.Sprintf("name: '%s'\n\n", template.Name))
+	// The 3.x formats dropped depends_on conditions, leaving only the start order
+	if spec == ComposeSpecV3 {
+		for i := range template.Services {
+			for j := range template.Services[i].DependsOn {
+				template.Services[i].DependsOn[j].Condition = ""
+			}
+		}
+	}
 
-	// Write services
-	if len(template.Services) > 0 {
-		buf.WriteString("services:\n")
+	// deploy, configs and secrets are Swarm features the 2.x formats never had
+	if spec == ComposeSpecV2 {
+		for i := range template.Services {
+			template.Services[i].Deploy = Deploy{}
+		}
+		template.Configs = nil
+		template.Secrets = nil
+	}
 
-		for _, service := range template.Services {
-			buf.WriteString(fmt.Sprintf("  %s:\n", service.Name))
+	return nil
+}
 
-			// Container name
-			if service.ContainerName != "" {
-				buf.WriteString(fmt.Sprintf("    container_name: %s\n", quote(service.ContainerName)))
-			}
+// primaryBinary returns the binary serving the detected port: the one holding the entrypoint, else the first
+func primaryBinary(project *detector.Project) string {
+	for _, b := range project.Binaries {
+		if b.Entrypoint == project.Entrypoint {
+			return b.Name
+		}
+	}
+	return project.Binaries[0].Name
+}
 
-			// Image
-			if service.Image != "" {
-				buf.WriteString(fmt.Sprintf("    image: %s\n", quote(service.Image)))
-			}
+// composePorts returns the port mappings of the primary service. The detected port is published on the
+// same host port unless a mapping in publish targets it; the other mappings publish additional ports.
+// Mappings take the [ip:][host:]container[/protocol] form of docker run -p.
+func composePorts(port int, publish []string) ([]string, error) {
+	var ports []string
+	overridden := false
+
+	for _, mapping := range publish {
+		container, err := containerPort(mapping)
+		if err != nil {
+			return nil, err
+		}
 
-			// Build
-			if service.Build.Context != "" {
-				buf.WriteString("    build:\n")
-				buf.WriteString(fmt.Sprintf("      context: %s\n", quote(service.Build.Context)))
-				if service.Build.Dockerfile != "" {
-					buf.WriteString(fmt.Sprintf("      dockerfile: %s\n", quote(service.Build.Dockerfile)))
-				}
-				if len(service.Build.Args) > 0 {
-					buf.WriteString("      args:\n")
-					for key, value := range service.Build.Args {
-						buf.WriteString(fmt.Sprintf("        %s: %s\n", quote(key), quote(value)))
-					}
-				}
-				if service.Build.Target != "" {
-					buf.WriteString(fmt.Sprintf("      target: %s\n", quote(service.Build.Target)))
-				}
-				if len(service.Build.CacheFrom) > 0 {
-					buf.WriteString("      cache_from:\n")
-					for _, cacheFrom := range service.Build.CacheFrom {
-						buf.WriteString(fmt.Sprintf("        - %s\n", quote(cacheFrom)))
-					}
-				}
-			}
+		if container == port {
+			overridden = true
+		}
 
-			// Restart policy
-			if service.Restart != "" {
-				buf.WriteString(fmt.Sprintf("    restart: %s\n", quote(service.Restart)))
+		// A bare container port is published on the same host port
+		if !strings.Contains(mapping, ":") {
+			number, protocol, found := strings.Cut(mapping, "/")
+			mapping = number + ":" + number
+			if found {
+				mapping += "/" + protocol
 			}
+		}
 
-			// Ports
-			if len(service.Ports) > 0 {
-				buf.WriteString("    ports:\n")
-				for _, port := range service.Ports {
-					buf.WriteString(fmt.Sprintf("      - %s\n", quote(port)))
-				}
-			}
+		ports = append(ports, mapping)
+	}
 
-			// Environment variables (as map)
-			if len(service.Environment) > 0 {
-				buf.WriteString("    environment:\n")
-				for key, value := range service.Environment {
-					buf.WriteString(fmt.Sprintf("      %s: %s\n", quote(key), quote(value)))
-				}
-			}
+	if port > 0 && !overridden {
+		ports = append([]string{fmt.Sprintf("%d:%d", port, port)}, ports...)
+	}
 
-			// Env files
-			if len(service.EnvFile) > 0 {
-				buf.WriteString("    env_file:\n")
-				for _, envFile := range service.EnvFile {
-					buf.WriteString(fmt.Sprintf("      - %s\n", quote(envFile)))
-				}
-			}
+	return ports, nil
+}
 
-			// Volumes
-			if len(service.Volumes) > 0 {
-				buf.WriteString("    volumes:\n")
-				for _, volume := range service.Volumes {
-					buf.WriteString(fmt.Sprintf("      - %s\n", quote(volume)))
-				}
-			}
+// containerPort validates a port mapping and returns its container port
+func containerPort(mapping string) (int, error) {
+	spec, protocol, _ := strings.Cut(mapping, "/")
+	if protocol != "" && protocol != "tcp" && protocol != "udp" && protocol != "sctp" {
+		return 0, fmt.Errorf("invalid port mapping %q: unknown protocol %s", mapping, protocol)
+	}
 
-			// Networks
-			if len(service.Networks) > 0 {
-				buf.WriteString("    networks:\n")
-				for _, network := range service.Networks {
-					buf.WriteString(fmt.Sprintf("      - %s\n", quote(network)))
-				}
-			}
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid port mapping %q, use [ip:][host:]container[/protocol]", mapping)
+	}
 
-			// Depends on
-			if len(service.DependsOn) > 0 {
-				buf.WriteString("    depends_on:\n")
-				for _, dependency := range service.DependsOn {
-					buf.WriteString(fmt.Sprintf("      - %s\n", quote(dependency)))
-				}
-			}
+	// The ip is only checked for presence, the host port may be empty to let Docker pick one
+	if len(parts) == 3 && parts[0] == "" {
+		return 0, fmt.Errorf("invalid port mapping %q: empty ip", mapping)
+	}
+	if len(parts) >= 2 {
+		if host := parts[len(parts)-2]; host != "" && !validPort(host) {
+			return 0, fmt.Errorf("invalid port mapping %q: bad host port %s", mapping, host)
+		}
+	}
 
-			// Health Check
-			if service.HealthCheck.Test != nil {
-				buf.WriteString("    healthcheck:\n")
-				buf.WriteString("      test:\n")
-				for _, testCmd := range service.HealthCheck.Test {
-					buf.WriteString(fmt.Sprintf("        - %s\n", quote(testCmd)))
-				}
-				if service.HealthCheck.Interval != "" {
-					buf.WriteString(fmt.Sprintf("      interval: %s\n", quote(service.HealthCheck.Interval)))
-				}
-				if service.HealthCheck.Timeout != "" {
-					buf.WriteString(fmt.Sprintf("      timeout: %s\n", quote(service.HealthCheck.Timeout)))
-				}
-				if service.HealthCheck.Retries != 0 {
-					buf.WriteString(fmt.Sprintf("      retries: %d\n", service.HealthCheck.Retries))
-				}
-				if service.HealthCheck.StartPeriod != "" {
-					buf.WriteString(fmt.Sprintf("      start_period: %s\n", quote(service.HealthCheck.StartPeriod)))
-				}
-			}
+	container := parts[len(parts)-1]
+	if !validPort(container) {
+		return 0, fmt.Errorf("invalid port mapping %q: bad container port %s", mapping, container)
+	}
 
-			// Deploy configuration
-			if service.Deploy.Mode != "" || service.Deploy.Replicas > 0 {
-				buf.WriteString("    deploy:\n")
-				if service.Deploy.Mode != "" {
-					buf.WriteString(fmt.Sprintf("      mode: %s\n", quote(service.Deploy.Mode)))
-				}
-				if service.Deploy.Replicas > 0 {
-					buf.WriteString(fmt.Sprintf("      replicas: %d\n", service.Deploy.Replicas))
-				}
-				// Add more deploy fields (resources, update_config, etc.) as needed
-			}
+	number, _ := strconv.Atoi(container)
+	return number, nil
+}
 
-			// Labels
-			if len(service.Labels) > 0 {
-				buf.WriteString("    labels:\n")
-				for key, value := range service.Labels {
-					buf.WriteString(fmt.Sprintf("      %s: %s\n", quote(key), quote(value)))
-				}
-			}
+func validPort(s string) bool {
+	number, err := strconv.Atoi(s)
+	return err == nil && number > 0 && number <= 65535
+}
 
-			// Command
-			if service.Command != "" {
-				buf.WriteString(fmt.Sprintf("    command: %s\n", quote(service.Command)))
-			}
+// appService returns a service that builds and runs the project itself
+func appService(name, containerName string, build Build) Service {
+	return Service{
+		Name:          name,
+		ContainerName: containerName,
+		Restart:       "unless-stopped",
+		Build:         build,
+		Environment: map[string]string{
+			"ENV": "development",
+		},
+	}
+}
 
-			// Entrypoint
-			if service.Entrypoint != "" {
-				buf.WriteString(fmt.Sprintf("    entrypoint: %s\n", quote(service.Entrypoint)))
-			}
+// imageEnvVars are set by the ENV instructions of the generated Dockerfile for each project type
+var imageEnvVars = map[detector.ProjectType][]string{
+	detector.NodeJS: {"NODE_ENV"},
+	detector.Python: {"PYTHONDONTWRITEBYTECODE", "PYTHONUNBUFFERED", "PATH"},
+}
 
-			// User
-			if service.User != "" {
-				buf.WriteString(fmt.Sprintf("    user: %s\n", quote(service.User)))
-			}
+// appEnvironment returns the variables the code reads with a default, interpolated from the shell or the
+// .env file next to docker-compose.yml and falling back to that default, and the names of the variables
+// without one. Those are passed through only when set, so lookups in the code still see them as unset.
+// PORT is fixed to the published port and variables the Dockerfile sets are left to the image.
+func appEnvironment(project *detector.Project) (map[string]string, []string) {
+	environment := map[string]string{}
+	var passThrough []string
+
+	for _, envVar := range project.EnvVars {
+		switch {
+		case slices.Contains(imageEnvVars[project.Type], envVar.Name):
+		case envVar.Name == "PORT" && project.Port > 0:
+			environment["PORT"] = strconv.Itoa(project.Port)
+		case envVar.Default == "":
+			passThrough = append(passThrough, envVar.Name)
+		default:
+			// Compose interpolates $ in values, $$ is a literal dollar sign
+			environment[envVar.Name] = fmt.Sprintf("${%s:-%s}", envVar.Name, strings.ReplaceAll(envVar.Default, "$", "$$"))
+		}
+	}
 
-			// Working directory
-			if service.WorkingDir != "" {
-				buf.WriteString(fmt.Sprintf("    working_dir: %s\n", quote(service.WorkingDir)))
-			}
+	return environment, passThrough
+}
 
-			// Read-only
-			if service.ReadOnly {
-				buf.WriteString("    read_only: true\n")
-			}
+// renderDockerCompose converts the template to YAML content. Maps are written with sorted keys and
+// every scalar is quoted as needed by the encoder, so the same template always gives the same bytes.
+func renderDockerCompose(template DockerComposeTemplate) (string, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(template); err != nil {
+		return "", fmt.Errorf("failed to encode docker-compose.yml: %v", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode docker-compose.yml: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// MarshalYAML writes the top-level sections, turning the named lists into mappings keyed by name in
+// their original order
+func (t DockerComposeTemplate) MarshalYAML() (interface{}, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
 
-			buf.WriteString("\n")
+	addScalar := func(key, value string) {
+		if value != "" {
+			root.Content = append(root.Content, scalarNode(key), scalarNode(value))
 		}
 	}
 
-	// Write networks
-	if len(template.Networks) > 0 {
-		buf.WriteString("networks:\n")
-		for _, network := range template.Networks {
-			buf.WriteString(fmt.Sprintf("  %s:\n", network.Name))
-			if network.Driver != "" {
-				buf.WriteString(fmt.Sprintf("    driver: %s\n", quote(network.Driver)))
-			}
-			if network.External {
-				buf.WriteString("    external: true\n")
-			}
-			if network.Attachable {
-				buf.WriteString("    attachable: true\n")
-			}
-			if len(network.Labels) > 0 {
-				buf.WriteString("    labels:\n")
-				for key, value := range network.Labels {
-					buf.WriteString(fmt.Sprintf("      %s: %s\n", quote(key), quote(value)))
-				}
+	addScalar("version", t.Version)
+	addScalar("name", t.Name)
+
+	sections := []struct {
+		key     string
+		entries []namedEntry
+	}{
+		{"services", namedEntries(t.Services, func(s Service) string { return s.Name })},
+		{"networks", namedEntries(t.Networks, func(n Network) string { return n.Name })},
+		{"volumes", namedEntries(t.Volumes, func(v Volume) string { return v.Name })},
+		{"configs", namedEntries(t.Configs, func(c Config) string { return c.Name })},
+		{"secrets", namedEntries(t.Secrets, func(s Secret) string { return s.Name })},
+	}
+
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+
+		mapping := &yaml.Node{Kind: yaml.MappingNode}
+		for _, entry := range section.entries {
+			var value yaml.Node
+			if err := value.Encode(entry.value); err != nil {
+				return nil, fmt.Errorf("failed to encode %s %s: %v", section.key, entry.name, err)
 			}
-			// IPAM configuration can be added here if needed
-			buf.WriteString("\n")
+			mapping.Content = append(mapping.Content, scalarNode(entry.name), &value)
 		}
+
+		root.Content = append(root.Content, scalarNode(section.key), mapping)
 	}
 
-	// Write volumes
-	if len(template.Volumes) > 0 {
-		buf.WriteString("volumes:\n")
-		for _, volume := range template.Volumes {
-			buf.WriteString(fmt.Sprintf("  %s:\n", volume.Name))
-			if volume.Driver != "" {
-				buf.WriteString(fmt.Sprintf("    driver: %s\n", quote(volume.Driver)))
-			}
-			if volume.External {
-				buf.WriteString("    external: true\n")
-			}
-			if len(volume.Labels) > 0 {
-				buf.WriteString("    labels:\n")
-				for key, value := range volume.Labels {
-					buf.WriteString(fmt.Sprintf("      %s: %s\n", quote(key), quote(value)))
-				}
-			}
-			if len(volume.Options) > 0 {
-				buf.WriteString("    options:\n")
-				for key, value := range volume.Options {
-					buf.WriteString(fmt.Sprintf("      %s: %s\n", quote(key), quote(value)))
-				}
+	return root, nil
+}
+
+// MarshalYAML double-quotes the port mappings, which YAML 1.1 parsers read as base-60 numbers otherwise,
+// and writes depends_on in its short list form unless a dependency has a condition
+func (s Service) MarshalYAML() (interface{}, error) {
+	// The alias has the fields and tags of Service without this method, so encoding it does not recurse
+	type service Service
+
+	var node yaml.Node
+	if err := node.Encode(service(s)); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "ports":
+			for _, port := range node.Content[i+1].Content {
+				port.Style = yaml.DoubleQuotedStyle
 			}
-			buf.WriteString("\n")
+		case "depends_on":
+			node.Content[i+1] = dependsOnNode(s.DependsOn)
 		}
 	}
 
-	// Write configs
-	if len(template.Configs) > 0 {
-		buf.WriteString("configs:\n")
-		for _, config := range template.Configs {
-			buf.WriteString(fmt.Sprintf("  %s:\n", config.Name))
-			if config.External {
-				buf.WriteString("    external: true\n")
-			} else {
-				buf.WriteString(fmt.Sprintf("    file: %s\n", quote(config.File)))
-			}
-			buf.WriteString("\n")
+	if len(s.PassEnvironment) > 0 {
+		addPassThrough(&node, s.Environment, s.PassEnvironment)
+	}
+
+	return &node, nil
+}
+
+// addPassThrough writes the pass-through variables into the environment mapping as keys without a
+// value, keeping the keys sorted. Variables with a value in environment take precedence.
+func addPassThrough(service *yaml.Node, environment map[string]string, names []string) {
+	var mapping *yaml.Node
+	for i := 0; i+1 < len(service.Content); i += 2 {
+		if service.Content[i].Value == "environment" {
+			mapping = service.Content[i+1]
 		}
 	}
+	if mapping == nil {
+		mapping = &yaml.Node{Kind: yaml.MappingNode}
+		service.Content = append(service.Content, scalarNode("environment"), mapping)
+	}
 
-	// Write secrets
-	if len(template.Secrets) > 0 {
-		buf.WriteString("secrets:\n")
-		for _, secret := range template.Secrets {
-			buf.WriteString(fmt.Sprintf("  %s:\n", secret.Name))
-			if secret.External {
-				buf.WriteString("    external: true\n")
-			} else {
-				buf.WriteString(fmt.Sprintf("    file: %s\n", quote(secret.File)))
-			}
-			buf.WriteString("\n")
+	for _, name := range names {
+		if _, ok := environment[name]; !ok {
+			mapping.Content = append(mapping.Content, scalarNode(name), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"})
 		}
 	}
 
-	return buf.String(), nil
+	pairs := make([][2]*yaml.Node, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+	slices.SortFunc(pairs, func(a, b [2]*yaml.Node) int { return strings.Compare(a[0].Value, b[0].Value) })
+
+	mapping.Content = mapping.Content[:0]
+	for _, pair := range pairs {
+		mapping.Content = append(mapping.Content, pair[0], pair[1])
+	}
+}
+
+func dependsOnNode(dependencies []Dependency) *yaml.Node {
+	conditions := false
+	for _, dependency := range dependencies {
+		conditions = conditions || dependency.Condition != ""
+	}
+
+	if !conditions {
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, dependency := range dependencies {
+			list.Content = append(list.Content, scalarNode(dependency.Service))
+		}
+		return list
+	}
+
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, dependency := range dependencies {
+		entry := &yaml.Node{Kind: yaml.MappingNode}
+		if dependency.Condition != "" {
+			entry.Content = append(entry.Content, scalarNode("condition"), scalarNode(dependency.Condition))
+		}
+		mapping.Content = append(mapping.Content, scalarNode(dependency.Service), entry)
+	}
+	return mapping
+}
+
+type namedEntry struct {
+	name  string
+	value interface{}
+}
+
+func namedEntries[T any](items []T, name func(T) string) []namedEntry {
+	entries := make([]namedEntry, len(items))
+	for i, item := range items {
+		entries[i] = namedEntry{name: name(item), value: item}
+	}
+	return entries
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
 }
@@ -2,36 +2,152 @@ package generator
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 
 	"github.com/Babatunde50/dockergen/internal/detector"
 )
 
+var (
+	// stageNamePattern is the rule BuildKit applies to stage names
+	stageNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-_.]*$`)
+
+	// goStages are the internal stages of the Go Dockerfile, which binary targets must not reuse
+	goStages = []string{"go-build", "go-runtime"}
+)
+
 // GenerateDockerfile creates a Dockerfile based on the detected project type
-func GenerateDockerfile(project *detector.Project, useMultiStage bool) (string, error) {
+func GenerateDockerfile(project *detector.Project, opts Options) (string, error) {
 	if project == nil {
 		return "", fmt.Errorf("project cannot be nil")
 	}
 
 	tmpl := DockerfileTemplate{
-		Port:          project.Port,
-		UseMultiStage: useMultiStage,
-		Version:       project.Version,
+		Port:           project.Port,
+		UseMultiStage:  opts.MultiStage,
+		Version:        project.Version,
+		PrivateModules: opts.PrivateModules,
+		RuntimeBase:    opts.RuntimeBase,
+	}
+
+	tmpl.Labels, tmpl.ImageVersion, tmpl.ImageRevision = imageLabels(project)
+
+	if opts.Binary != "" && project.Type != detector.Go {
+		return "", fmt.Errorf("selecting a binary is only supported for Go projects")
+	}
+
+	if opts.RuntimeBase != "" && project.Type != detector.Go {
+		return "", fmt.Errorf("choosing a runtime base is only supported for Go projects")
+	}
+
+	// Single-stage builds run from the golang build image, so there is no runtime stage to base
+	if opts.RuntimeBase != "" && !opts.MultiStage {
+		return "", fmt.Errorf("choosing a runtime base requires a multi-stage build")
+	}
+
+	if err := validatePlatforms(opts.Platforms); err != nil {
+		return "", err
+	}
+
+	switch opts.PrivateModules {
+	case "", PrivateModulesSSH, PrivateModulesNetrc:
+	default:
+		return "", fmt.Errorf("unsupported private modules mode %q, use %s or %s", opts.PrivateModules, PrivateModulesSSH, PrivateModulesNetrc)
 	}
 
+	var content string
+	var err error
+
 	switch project.Type {
 	case detector.Go:
-		return generateGoDockerfile(project, tmpl)
+		content, err = generateGoDockerfile(project, tmpl, opts.Binary, opts.CacheMounts)
+	case detector.NodeJS:
+		content, err = generateNodeJSDockerfile(project, tmpl, opts.CacheMounts)
+	case detector.Python:
+		content, err = generatePythonDockerfile(project, tmpl, opts.CacheMounts)
 	default:
 		return "", fmt.Errorf("unsupported project type: %s", project.Type)
 	}
+
+	if err != nil || opts.ImageLock == nil {
+		return content, err
+	}
+
+	return opts.ImageLock.Pin(content)
 }
 
-// generateGoDockerfile creates a Dockerfile for Go projects
-func generateGoDockerfile(project *detector.Project, tmpl DockerfileTemplate) (string, error) {
+// generateGoDockerfile creates a Dockerfile for Go projects. Projects with several binaries under cmd/
+// get a named target stage per binary unless a single binary is selected.
+func generateGoDockerfile(project *detector.Project, tmpl DockerfileTemplate, binary string, cacheMounts bool) (string, error) {
+
+	layout := newGoLayout(project)
+	tmpl.ModuleCopies = layout.moduleCopies
+	tmpl.BuildDir = layout.buildDir
+	tmpl.CGO = project.CGO
+	tmpl.Vendored = project.Vendored
+	tmpl.StampVersion = len(project.VersionVars) > 0
+
+	// Vendored builds never download, so they need no credentials either
+	if tmpl.Vendored {
+		tmpl.PrivateModules = ""
+	}
+
+	if cacheMounts {
+		tmpl.DownloadMounts = cacheMount("/go/pkg/mod")
+		tmpl.BuildMounts = cacheMount("/root/.cache/go-build")
+		if !tmpl.Vendored {
+			tmpl.BuildMounts = cacheMount("/go/pkg/mod", "/root/.cache/go-build")
+		}
+	}
+
+	switch tmpl.PrivateModules {
+	case PrivateModulesSSH:
+		tmpl.DownloadMounts += "--mount=type=ssh "
+	case PrivateModulesNetrc:
+		tmpl.DownloadMounts += "--mount=type=secret,id=netrc,target=/root/.netrc "
+	}
+
+	if err := setGoImages(&tmpl); err != nil {
+		return "", err
+	}
+
+	// A static binary cross-compiles, so buildx runs the compiler natively instead of under emulation.
+	// cgo would need a cross C toolchain, and the single-stage image must match the target anyway.
+	tmpl.CrossCompile = tmpl.UseMultiStage && !tmpl.CGO
+
+	if binary != "" {
+		selected, ok := findBinary(project.Binaries, binary)
+		if !ok {
+			return "", fmt.Errorf("no main package for binary %s under cmd/", binary)
+		}
+
+		tmpl.BinaryName = selected.Name
+		tmpl.Entrypoint = fmt.Sprintf("/app/%s", selected.Name)
+		tmpl.BuildCmd = goBuildCmd(tmpl, project.VersionVars, selected.Name, layout.packagePath(selected.Entrypoint))
+		tmpl.RunCmd = fmt.Sprintf("/app/%s", selected.Name)
+
+		return renderDockerfile(goDockerfileTemplate, tmpl)
+	}
+
+	if len(project.Binaries) > 1 {
+		for _, b := range project.Binaries {
+			if err := validateStageName(b.Name); err != nil {
+				return "", err
+			}
+			tmpl.Targets = append(tmpl.Targets, Target{
+				Name:     b.Name,
+				BuildCmd: goBuildCmd(tmpl, project.VersionVars, b.Name, layout.packagePath(b.Entrypoint)),
+			})
+		}
+
+		return renderDockerfile(goDockerfileTemplate, tmpl)
+	}
 
 	// Extract binary name from entrypoint path
 	binaryName := "app"
@@ -49,13 +165,397 @@ func generateGoDockerfile(project *detector.Project, tmpl DockerfileTemplate) (s
 
 	tmpl.Entrypoint = fmt.Sprintf("/app/%s", binaryName)
 
-	tmpl.BuildCmd = fmt.Sprintf("CGO_ENABLED=0 go build -ldflags=\"-s -w\" -o /app/%s ./%s", binaryName, trimLastPart(project.Entrypoint))
+	tmpl.BuildCmd = goBuildCmd(tmpl, project.VersionVars, binaryName, layout.packagePath(project.Entrypoint))
 
 	tmpl.RunCmd = fmt.Sprintf("/app/%s", binaryName)
 
 	return renderDockerfile(goDockerfileTemplate, tmpl)
 }
 
+// validateStageName checks that a binary can name its target stage. BuildKit only accepts lowercase
+// stage names, and the stages the Go Dockerfile builds and runs in are taken.
+func validateStageName(name string) error {
+	if !stageNamePattern.MatchString(name) {
+		return fmt.Errorf("binary %s cannot name a build stage: use lowercase letters, digits, '-', '_' and '.', starting with a letter, or write a Dockerfile per binary with --per-binary", name)
+	}
+	if slices.Contains(goStages, name) {
+		return fmt.Errorf("binary %s clashes with the %s stage of the Dockerfile, write a Dockerfile per binary with --per-binary", name, name)
+	}
+	return nil
+}
+
+// setGoImages picks the build and runtime images for the runtime base. Static binaries run on any
+// base; cgo binaries need a libc, so they build on Alpine for the musl runtime and on Debian for the
+// glibc ones. Images without a shell get the conventional nonroot UID instead of a created user.
+func setGoImages(tmpl *DockerfileTemplate) error {
+	if tmpl.RuntimeBase == "" {
+		tmpl.RuntimeBase = RuntimeAlpine
+	}
+
+	tmpl.BuildImage = fmt.Sprintf("golang:%s-alpine", tmpl.Version)
+	tmpl.RuntimeUser = "65532:65532"
+
+	switch tmpl.RuntimeBase {
+	case RuntimeAlpine:
+		tmpl.RuntimeImage = "alpine:3.22"
+		tmpl.RuntimeUser = "appuser"
+	case RuntimeDistrolessStatic:
+		tmpl.RuntimeImage = "gcr.io/distroless/static-debian12:nonroot"
+	case RuntimeDistrolessBase:
+		tmpl.RuntimeImage = "gcr.io/distroless/base-debian12:nonroot"
+	case RuntimeScratch:
+		tmpl.RuntimeImage = "scratch"
+	case RuntimeChainguard:
+		tmpl.RuntimeImage = "cgr.dev/chainguard/static:latest"
+		if tmpl.CGO {
+			tmpl.RuntimeImage = "cgr.dev/chainguard/glibc-dynamic:latest"
+		}
+	default:
+		return fmt.Errorf("unsupported runtime base %q, use one of %s", tmpl.RuntimeBase, strings.Join(RuntimeBases, ", "))
+	}
+
+	// The single-stage build runs in the build image, so only the multi-stage build has to match libcs
+	if !tmpl.CGO || !tmpl.UseMultiStage {
+		return nil
+	}
+
+	switch tmpl.RuntimeBase {
+	case RuntimeDistrolessStatic, RuntimeScratch:
+		return fmt.Errorf("the project uses cgo, which needs a libc that %s does not have; use %s, %s or %s",
+			tmpl.RuntimeBase, RuntimeAlpine, RuntimeDistrolessBase, RuntimeChainguard)
+	case RuntimeDistrolessBase, RuntimeChainguard:
+		tmpl.BuildImage = fmt.Sprintf("golang:%s-bookworm", tmpl.Version)
+		tmpl.DebianBuilder = true
+	}
+
+	return nil
+}
+
+// goBuildCmd builds the package at pkg into /app/<binaryName>. Without cgo the binary is fully static;
+// with it the binary links against the libc of the build image. Version variables are set from the
+// build args, keeping their value in the source when the arg is empty.
+func goBuildCmd(tmpl DockerfileTemplate, versionVars []detector.VersionVar, binaryName, pkg string) string {
+	cgoEnabled := 0
+	if tmpl.CGO {
+		cgoEnabled = 1
+	}
+
+	flags := ""
+	if tmpl.Vendored {
+		flags = " -mod=vendor"
+	}
+
+	target := ""
+	if tmpl.CrossCompile {
+		target = " GOOS=$TARGETOS GOARCH=$TARGETARCH"
+	}
+
+	ldflags := "-s -w"
+	for _, v := range versionVars {
+		ldflags += fmt.Sprintf(" -X '%s=${%s:-%s}'", v.Symbol, v.Arg, v.Default)
+	}
+
+	return fmt.Sprintf("CGO_ENABLED=%d%s go build%s -ldflags=\"%s\" -o /app/%s %s", cgoEnabled, target, flags, ldflags, binaryName, pkg)
+}
+
+// validatePlatforms checks that every platform has the os/arch[/variant] form buildx expects
+func validatePlatforms(platforms []string) error {
+	for _, platform := range platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+			return fmt.Errorf("invalid platform %q, use os/arch or os/arch/variant such as linux/arm64", platform)
+		}
+	}
+	return nil
+}
+
+// goLayout describes where the Go module files and packages sit inside the build context
+type goLayout struct {
+	moduleCopies []string // COPY arguments for the go.work, go.mod and go.sum files
+	buildDir     string   // Module directory go build runs in, relative to the context; empty for the root
+	workDir      string   // Project directory relative to the context
+}
+
+// newGoLayout works out the layout for a project. Workspace projects use the workspace root as the
+// build context so every module the build needs is available, and build from the selected module.
+func newGoLayout(project *detector.Project) goLayout {
+	if project.Workspace == nil {
+		return goLayout{
+			moduleCopies: []string{"go.mod go.sum* ./"},
+			workDir:      ".",
+		}
+	}
+
+	layout := goLayout{
+		moduleCopies: []string{"go.work go.work.sum* ./"},
+		workDir:      relSlash(project.Workspace.Root, project.WorkDir),
+	}
+
+	for _, module := range project.Workspace.Modules {
+		if module == "." {
+			layout.moduleCopies = append(layout.moduleCopies, "go.mod go.sum* ./")
+			continue
+		}
+		layout.moduleCopies = append(layout.moduleCopies, fmt.Sprintf("%s/go.mod %s/go.sum* ./%s/", module, module, module))
+	}
+
+	if project.Workspace.Module != "." {
+		layout.buildDir = project.Workspace.Module
+	}
+
+	return layout
+}
+
+// packagePath returns the go build argument for the package containing entrypoint
+func (l goLayout) packagePath(entrypoint string) string {
+	pkg := path.Join(l.workDir, path.Dir(filepath.ToSlash(entrypoint)))
+
+	if l.buildDir != "" {
+		rel, err := filepath.Rel(l.buildDir, pkg)
+		if err == nil {
+			pkg = filepath.ToSlash(rel)
+		}
+	}
+
+	if pkg == "." {
+		return "./"
+	}
+
+	return "./" + pkg + "/"
+}
+
+// buildContext returns the docker build context relative to the project directory and the
+// Dockerfile directory relative to that context
+func buildContext(project *detector.Project) (string, string) {
+	if project.Workspace == nil {
+		return ".", ""
+	}
+
+	return relSlash(project.WorkDir, project.Workspace.Root), relSlash(project.Workspace.Root, project.WorkDir)
+}
+
+// relSlash is filepath.Rel with a slash-separated result, falling back to "." on error
+func relSlash(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "."
+	}
+	return filepath.ToSlash(rel)
+}
+
+func findBinary(binaries []detector.Binary, name string) (detector.Binary, bool) {
+	for _, b := range binaries {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return detector.Binary{}, false
+}
+
+// generateNodeJSDockerfile creates a Dockerfile for Node.js projects
+func generateNodeJSDockerfile(project *detector.Project, tmpl DockerfileTemplate, cacheMounts bool) (string, error) {
+
+	if tmpl.Version == "" {
+		tmpl.Version = detector.DefaultNodeVersion
+	}
+
+	tmpl.DependencyFiles, tmpl.InstallCmd, tmpl.ProdInstallCmd = nodeInstallSteps(project.PackageManager, project.LockFile != "")
+
+	if cacheMounts {
+		tmpl.InstallMounts = cacheMount(nodeCacheDir(project.PackageManager))
+	}
+
+	// npm can run the build script whichever package manager installed the dependencies
+	tmpl.BuildCmd = "npm run build --if-present"
+
+	if project.Entrypoint != "" {
+		tmpl.Cmd = execForm("node", filepath.ToSlash(project.Entrypoint))
+	} else {
+		tmpl.Cmd = execForm("npm", "start")
+	}
+
+	return renderDockerfile(nodeDockerfileTemplate, tmpl)
+}
+
+// nodeInstallSteps returns the files to copy and the full and production-only install commands for a
+// package manager. Frozen installs need a lockfile, so projects without one get a regular install.
+func nodeInstallSteps(manager detector.PackageManager, hasLockFile bool) (files, install, prodInstall string) {
+	switch manager {
+	case detector.Yarn:
+		files = "package.json yarn.lock*"
+		install = "yarn install"
+		if hasLockFile {
+			install += " --frozen-lockfile"
+		}
+		prodInstall = install + " --production"
+	case detector.YarnBerry:
+		files = "package.json yarn.lock* .yarnrc.yml*"
+		install = "corepack enable && yarn install"
+		if hasLockFile {
+			install += " --immutable"
+		}
+		prodInstall = "corepack enable && yarn workspaces focus --all --production"
+	case detector.Pnpm:
+		files = "package.json pnpm-lock.yaml* pnpm-workspace.yaml*"
+		install = "corepack enable && pnpm install"
+		if hasLockFile {
+			install += " --frozen-lockfile"
+		}
+		prodInstall = install + " --prod"
+	case detector.Bun:
+		files = "package.json bun.lockb* bun.lock*"
+		install = "npm install -g bun && bun install"
+		if hasLockFile {
+			install += " --frozen-lockfile"
+		}
+		prodInstall = install + " --production"
+	default:
+		// npm ci refuses to run without a lockfile, so fall back to npm install
+		files = "package.json package-lock.json* npm-shrinkwrap.json*"
+		install = "npm install"
+		if hasLockFile {
+			install = "npm ci"
+		}
+		prodInstall = install + " --omit=dev"
+	}
+
+	return files, install, prodInstall
+}
+
+// nodeCacheDir returns where a package manager keeps its download cache when running as root
+func nodeCacheDir(manager detector.PackageManager) string {
+	switch manager {
+	case detector.Yarn:
+		return "/usr/local/share/.cache/yarn"
+	case detector.YarnBerry:
+		return "/root/.yarn/berry/cache"
+	case detector.Pnpm:
+		return "/root/.local/share/pnpm/store"
+	case detector.Bun:
+		return "/root/.bun/install/cache"
+	default:
+		return "/root/.npm"
+	}
+}
+
+// generatePythonDockerfile creates a Dockerfile for Python projects
+func generatePythonDockerfile(project *detector.Project, tmpl DockerfileTemplate, cacheMounts bool) (string, error) {
+
+	if tmpl.Version == "" {
+		tmpl.Version = detector.DefaultPythonVersion
+	}
+
+	tmpl.ToolInstallCmd, tmpl.DependencyFiles, tmpl.InstallCmd = pythonInstallSteps(project.PackageManager, project.LockFile != "", project.Installable, cacheMounts)
+
+	if cacheMounts {
+		tmpl.InstallMounts = cacheMount("/root/.cache/pip")
+		if project.PackageManager == detector.Uv {
+			tmpl.InstallMounts = cacheMount("/root/.cache/pip", "/root/.cache/uv")
+		}
+	}
+
+	if project.Entrypoint != "" {
+		tmpl.Cmd = execForm("python", filepath.ToSlash(project.Entrypoint))
+	} else {
+		tmpl.Cmd = execForm("python", "-m", "app")
+	}
+
+	return renderDockerfile(pythonDockerfileTemplate, tmpl)
+}
+
+// pythonInstallSteps returns the command installing the package manager itself, the files to copy and the
+// command installing the dependencies into the active virtual environment. Tools that cannot install into
+// an existing environment export a requirements file for pip instead. An empty file list means the whole
+// project has to be copied because it gets installed as a package. Plain scripts without requirements.txt,
+// setup.py or pyproject.toml have nothing to install. With a cache mount pip keeps its cache.
+func pythonInstallSteps(manager detector.PackageManager, hasLockFile, installable, cached bool) (toolInstall, files, install string) {
+	pipInstall := "pip install --no-cache-dir"
+	if cached {
+		pipInstall = "pip install"
+	}
+
+	// Exporting needs a lockfile, so generate one on the fly when the project does not commit it
+	lockFirst := func(lockCmd string) string {
+		if hasLockFile {
+			return ""
+		}
+		return lockCmd + " && "
+	}
+
+	switch manager {
+	case detector.Poetry:
+		toolInstall = pipInstall + " poetry poetry-plugin-export"
+		files = "pyproject.toml poetry.lock*"
+		install = lockFirst("poetry lock") +
+			"poetry export --format requirements.txt --output /tmp/requirements.txt" +
+			" && " + pipInstall + " -r /tmp/requirements.txt"
+	case detector.Pipenv:
+		toolInstall = pipInstall + " pipenv"
+		files = "Pipfile Pipfile.lock*"
+		install = lockFirst("pipenv lock") +
+			"pipenv requirements > /tmp/requirements.txt" +
+			" && " + pipInstall + " -r /tmp/requirements.txt"
+	case detector.Uv:
+		toolInstall = pipInstall + " uv"
+		files = "pyproject.toml uv.lock*"
+		install = "UV_PROJECT_ENVIRONMENT=/opt/venv uv sync --no-dev --no-install-project"
+		if cached {
+			// The cache mount is a different filesystem than the venv, so uv cannot hardlink from it
+			install = "UV_LINK_MODE=copy " + install
+		}
+		if hasLockFile {
+			install += " --frozen"
+		}
+	case detector.PDM:
+		toolInstall = pipInstall + " pdm"
+		files = "pyproject.toml pdm.lock*"
+		install = lockFirst("pdm lock") +
+			"pdm export --prod --output /tmp/requirements.txt" +
+			" && " + pipInstall + " -r /tmp/requirements.txt"
+	default:
+		if hasLockFile {
+			files = "requirements.txt"
+			install = pipInstall + " -r requirements.txt"
+		} else if installable {
+			install = pipInstall + " ."
+		}
+	}
+
+	return toolInstall, files, install
+}
+
+// imageLabels returns the static OCI labels and the defaults of the VERSION and COMMIT build args
+func imageLabels(project *detector.Project) ([]Label, string, string) {
+	meta := project.Metadata
+
+	title := meta.Name
+	if title == "" {
+		title = filepath.Base(project.WorkDir)
+	}
+
+	var labels []Label
+	add := func(key, value string) {
+		if value != "" {
+			labels = append(labels, Label{Key: "org.opencontainers.image." + key, Value: dockerQuote(value)})
+		}
+	}
+
+	add("title", title)
+	add("description", meta.Description)
+	add("source", meta.Source)
+	add("url", meta.Source)
+	add("licenses", meta.License)
+
+	return labels, dockerQuote(meta.Version), dockerQuote(meta.Revision)
+}
+
+// dockerQuote double-quotes s for a Dockerfile instruction, escaping quotes, backslashes and
+// variable references so the value is taken literally
+func dockerQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
 // renderDockerfile applies the template data to the specified template
 func renderDockerfile(dockerfileTemplate string, tmpl DockerfileTemplate) (string, error) {
 	t, err := template.New("dockerfile").Parse(dockerfileTemplate)
@@ -63,6 +563,10 @@ func renderDockerfile(dockerfileTemplate string, tmpl DockerfileTemplate) (strin
 		return "", fmt.Errorf("failed to parse dockerfile template: %v", err)
 	}
 
+	if _, err := t.Parse(metadataTemplates); err != nil {
+		return "", fmt.Errorf("failed to parse metadata templates: %v", err)
+	}
+
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, tmpl); err != nil {
 		return "", fmt.Errorf("failed to execute dockerfile template: %v", err)
@@ -71,7 +575,21 @@ func renderDockerfile(dockerfileTemplate string, tmpl DockerfileTemplate) (strin
 	return buf.String(), nil
 }
 
-func trimLastPart(path string) string {
-	dir := filepath.Dir(path)
-	return dir + string(filepath.Separator)
+// cacheMount returns RUN --mount flags for BuildKit cache mounts at targets
+func cacheMount(targets ...string) string {
+	var mounts strings.Builder
+	for _, target := range targets {
+		mounts.WriteString(fmt.Sprintf("--mount=type=cache,target=%s ", target))
+	}
+	return mounts.String()
+}
+
+// execForm renders args as a JSON array suitable for exec-form CMD and ENTRYPOINT instructions
+func execForm(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		data, _ := json.Marshal(arg)
+		quoted[i] = string(data)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
 }
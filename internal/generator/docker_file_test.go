@@ -0,0 +1,566 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+)
+
+// assertDockerfile fails the test unless the Dockerfile contains every expected line and none of the
+// unexpected ones
+func assertDockerfile(t *testing.T, content string, contains, omits []string) {
+	t.Helper()
+
+	for _, expected := range contains {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected Dockerfile to contain %q, got:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range omits {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected Dockerfile without %q, got:\n%s", unexpected, content)
+		}
+	}
+}
+
+func TestGeneratePythonDockerfileInstall(t *testing.T) {
+	tests := []struct {
+		name        string
+		lockFile    string
+		installable bool
+		contains    []string
+		omits       []string
+	}{
+		{
+			name:     "requirements.txt",
+			lockFile: "requirements.txt",
+			contains: []string{"COPY requirements.txt ./", "RUN pip install --no-cache-dir -r requirements.txt"},
+		},
+		{
+			name:        "Installable package",
+			installable: true,
+			contains:    []string{"RUN pip install --no-cache-dir ."},
+		},
+		{
+			name:  "Plain script",
+			omits: []string{"pip install"},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, multiStage := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s multi-stage=%v", tc.name, multiStage), func(t *testing.T) {
+				project := &detector.Project{
+					Type:           detector.Python,
+					Entrypoint:     "main.py",
+					Port:           8000,
+					Version:        "3.12",
+					PackageManager: detector.Pip,
+					LockFile:       tc.lockFile,
+					Installable:    tc.installable,
+				}
+
+				content, err := GenerateDockerfile(project, Options{MultiStage: multiStage})
+				if err != nil {
+					t.Fatalf("GenerateDockerfile failed: %v", err)
+				}
+
+				assertDockerfile(t, content, append(slices.Clone(tc.contains), `CMD ["python", "main.py"]`), tc.omits)
+			})
+		}
+	}
+}
+
+func TestGenerateDockerfileLabels(t *testing.T) {
+	project := &detector.Project{
+		Type:       detector.NodeJS,
+		Entrypoint: "index.js",
+		Port:       3000,
+		Version:    "22",
+		WorkDir:    "/src/web",
+		Metadata: detector.Metadata{
+			Name:     "web",
+			Version:  "2.1.0",
+			License:  "MIT",
+			Source:   "https://github.com/acme/web",
+			Revision: "0123456789abcdef0123456789abcdef01234567",
+		},
+	}
+
+	content, err := GenerateDockerfile(project, Options{MultiStage: true})
+	if err != nil {
+		t.Fatalf("GenerateDockerfile failed: %v", err)
+	}
+
+	assertDockerfile(t, content, []string{
+		"ARG VERSION=\"2.1.0\"\nARG COMMIT=\"0123456789abcdef0123456789abcdef01234567\"\nARG DATE\n",
+		`LABEL org.opencontainers.image.title="web"`,
+		`LABEL org.opencontainers.image.source="https://github.com/acme/web"`,
+		`LABEL org.opencontainers.image.licenses="MIT"`,
+		`LABEL org.opencontainers.image.revision="$COMMIT"`,
+	}, nil)
+}
+
+func TestGenerateDockerfileLabelsAfterRun(t *testing.T) {
+	projects := []*detector.Project{
+		{Type: detector.Go, Entrypoint: "main.go", Version: "1.22", Port: 8080},
+		{Type: detector.Go, Version: "1.22", Port: 8080, Binaries: []detector.Binary{
+			{Name: "api", Entrypoint: "cmd/api/main.go"},
+			{Name: "worker", Entrypoint: "cmd/worker/main.go"},
+		}},
+		{Type: detector.NodeJS, Entrypoint: "index.js", Port: 3000, PackageManager: detector.Npm},
+		{Type: detector.Python, Entrypoint: "main.py", Port: 8000, PackageManager: detector.Pip, LockFile: "requirements.txt"},
+	}
+
+	for _, project := range projects {
+		for _, multiStage := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s %d binaries multi-stage=%v", project.Type, len(project.Binaries), multiStage), func(t *testing.T) {
+				content, err := GenerateDockerfile(project, Options{MultiStage: multiStage})
+				if err != nil {
+					t.Fatalf("GenerateDockerfile failed: %v", err)
+				}
+
+				// COMMIT and DATE change with every CI build, so any RUN after them would never be cached
+				_, rest, ok := strings.Cut(content, "\nARG COMMIT\n")
+				if !ok {
+					t.Fatalf("Expected the final stage to declare ARG COMMIT, got:\n%s", content)
+				}
+				if strings.Contains(rest, "\nRUN ") {
+					t.Errorf("Expected no RUN after ARG COMMIT, got:\n%s", content)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateGoDockerfileTargets(t *testing.T) {
+	newProject := func(names ...string) *detector.Project {
+		project := &detector.Project{Type: detector.Go, Version: "1.22", Port: 8080}
+		for _, name := range names {
+			project.Binaries = append(project.Binaries, detector.Binary{Name: name, Entrypoint: "cmd/" + name + "/main.go"})
+		}
+		project.Entrypoint = project.Binaries[0].Entrypoint
+		return project
+	}
+
+	content, err := GenerateDockerfile(newProject("build", "runtime"), Options{MultiStage: true})
+	if err != nil {
+		t.Fatalf("GenerateDockerfile failed: %v", err)
+	}
+	assertDockerfile(t, content, []string{
+		" AS go-build\n",
+		" AS go-runtime\n",
+		"FROM go-runtime AS build\nCOPY --from=go-build /app/build /app/\n",
+		"FROM go-runtime AS runtime\nCOPY --from=go-build /app/runtime /app/\n",
+	}, nil)
+
+	for _, names := range [][]string{{"api", "Worker"}, {"api", "2fa"}, {"api", "go-build"}} {
+		if _, err := GenerateDockerfile(newProject(names...), Options{MultiStage: true}); err == nil {
+			t.Errorf("Expected an error for binary %s", names[1])
+		}
+	}
+
+	// A Dockerfile per binary has no target stages to name
+	if _, err := GenerateDockerfile(newProject("api", "Worker"), Options{MultiStage: true, Binary: "Worker"}); err != nil {
+		t.Errorf("Expected a Dockerfile for a single binary, got %v", err)
+	}
+}
+
+func TestGenerateNodeJSDockerfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		manager    detector.PackageManager
+		lockFile   string
+		entrypoint string
+		multiStage bool
+		contains   []string
+	}{
+		{
+			name:       "npm with a lockfile",
+			manager:    detector.Npm,
+			lockFile:   "package-lock.json",
+			entrypoint: "server.js",
+			multiStage: true,
+			contains: []string{
+				"FROM node:20-alpine AS deps\n",
+				"COPY package.json package-lock.json* npm-shrinkwrap.json* ./\nRUN npm ci\n",
+				"RUN npm ci --omit=dev\n",
+				"COPY --from=build --chown=node:node /app ./\n",
+				"USER node\n",
+				"EXPOSE 3000\n",
+				`CMD ["node", "server.js"]`,
+			},
+		},
+		{
+			name:       "npm without a lockfile",
+			manager:    detector.Npm,
+			multiStage: true,
+			contains:   []string{"RUN npm install\n", "RUN npm install --omit=dev\n", `CMD ["npm", "start"]`},
+		},
+		{
+			name:       "pnpm",
+			manager:    detector.Pnpm,
+			lockFile:   "pnpm-lock.yaml",
+			multiStage: true,
+			contains:   []string{"RUN corepack enable && pnpm install --frozen-lockfile\n", "RUN corepack enable && pnpm install --frozen-lockfile --prod\n"},
+		},
+		{
+			name:     "Single-stage yarn",
+			manager:  detector.Yarn,
+			lockFile: "yarn.lock",
+			contains: []string{"COPY package.json yarn.lock* ./\nRUN yarn install --frozen-lockfile\n", "RUN npm run build --if-present\n", "ENV NODE_ENV=production\n"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			project := &detector.Project{
+				Type:           detector.NodeJS,
+				Entrypoint:     tc.entrypoint,
+				Port:           3000,
+				Version:        "20",
+				PackageManager: tc.manager,
+				LockFile:       tc.lockFile,
+			}
+
+			content, err := GenerateDockerfile(project, Options{MultiStage: tc.multiStage})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, tc.contains, nil)
+		})
+	}
+
+	// Projects built without detection still get an LTS image
+	content, err := GenerateDockerfile(&detector.Project{Type: detector.NodeJS}, Options{})
+	if err != nil {
+		t.Fatalf("GenerateDockerfile failed: %v", err)
+	}
+	assertDockerfile(t, content, []string{"FROM node:" + detector.DefaultNodeVersion + "-alpine\n"}, nil)
+}
+
+func TestGeneratePythonDockerfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		manager    detector.PackageManager
+		lockFile   string
+		entrypoint string
+		contains   []string
+	}{
+		{
+			name:       "pip",
+			manager:    detector.Pip,
+			lockFile:   "requirements.txt",
+			entrypoint: "app/main.py",
+			contains: []string{
+				"FROM python:3.11-slim AS build\n",
+				"RUN python -m venv /opt/venv\n",
+				"COPY --from=build /opt/venv /opt/venv\n",
+				"USER appuser\n",
+				"EXPOSE 8000\n",
+				`CMD ["python", "app/main.py"]`,
+			},
+		},
+		{
+			name:     "Poetry without a lockfile",
+			manager:  detector.Poetry,
+			contains: []string{"RUN pip install --no-cache-dir poetry poetry-plugin-export\n", "COPY pyproject.toml poetry.lock* ./\n", "RUN poetry lock && poetry export"},
+		},
+		{
+			name:     "uv with a lockfile",
+			manager:  detector.Uv,
+			lockFile: "uv.lock",
+			contains: []string{"RUN pip install --no-cache-dir uv\n", "RUN UV_PROJECT_ENVIRONMENT=/opt/venv uv sync --no-dev --no-install-project --frozen\n"},
+		},
+		{
+			name:     "Pipenv",
+			manager:  detector.Pipenv,
+			lockFile: "Pipfile.lock",
+			contains: []string{"COPY Pipfile Pipfile.lock* ./\n", "RUN pipenv requirements > /tmp/requirements.txt && pip install --no-cache-dir -r /tmp/requirements.txt\n", `CMD ["python", "-m", "app"]`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			project := &detector.Project{
+				Type:           detector.Python,
+				Entrypoint:     tc.entrypoint,
+				Port:           8000,
+				Version:        "3.11",
+				PackageManager: tc.manager,
+				LockFile:       tc.lockFile,
+			}
+
+			content, err := GenerateDockerfile(project, Options{MultiStage: true})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, tc.contains, nil)
+		})
+	}
+
+	content, err := GenerateDockerfile(&detector.Project{Type: detector.Python}, Options{})
+	if err != nil {
+		t.Fatalf("GenerateDockerfile failed: %v", err)
+	}
+	assertDockerfile(t, content, []string{"FROM python:" + detector.DefaultPythonVersion + "-slim\n"}, nil)
+}
+
+func TestGenerateGoDockerfileRuntimeBase(t *testing.T) {
+	tests := []struct {
+		runtimeBase string
+		cgo         bool
+		contains    []string
+		omits       []string
+		wantErr     bool
+	}{
+		{
+			runtimeBase: "",
+			contains:    []string{"FROM alpine:3.22\n", "RUN addgroup -S appgroup && adduser -S appuser -G appgroup\n", "USER appuser\n"},
+		},
+		{
+			runtimeBase: RuntimeScratch,
+			contains: []string{
+				"RUN apk add --no-cache ca-certificates tzdata\n",
+				"FROM scratch\n",
+				"COPY --from=go-build /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/\n",
+				"COPY --from=go-build /usr/share/zoneinfo /usr/share/zoneinfo\n",
+				"USER 65532:65532\n",
+			},
+			omits: []string{"adduser"},
+		},
+		{
+			runtimeBase: RuntimeDistrolessStatic,
+			contains:    []string{"FROM gcr.io/distroless/static-debian12:nonroot\n", "USER 65532:65532\n"},
+			omits:       []string{"adduser", "apk add --no-cache ca-certificates"},
+		},
+		{
+			runtimeBase: RuntimeDistrolessBase,
+			cgo:         true,
+			contains:    []string{"FROM golang:1.22-bookworm AS go-build\n", "FROM gcr.io/distroless/base-debian12:nonroot\n", "CGO_ENABLED=1 go build"},
+			omits:       []string{"musl-dev"},
+		},
+		{
+			runtimeBase: RuntimeChainguard,
+			cgo:         true,
+			contains:    []string{"FROM cgr.dev/chainguard/glibc-dynamic:latest\n", "USER 65532:65532\n"},
+		},
+		{runtimeBase: RuntimeScratch, cgo: true, wantErr: true},
+		{runtimeBase: "busybox", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s cgo=%v", tc.runtimeBase, tc.cgo), func(t *testing.T) {
+			project := &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22", Port: 8080, CGO: tc.cgo}
+
+			content, err := GenerateDockerfile(project, Options{MultiStage: true, RuntimeBase: tc.runtimeBase})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got:\n%s", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, tc.contains, tc.omits)
+		})
+	}
+
+	if _, err := GenerateDockerfile(&detector.Project{Type: detector.NodeJS}, Options{RuntimeBase: RuntimeScratch}); err == nil {
+		t.Error("Expected an error for a runtime base on a Node.js project")
+	}
+
+	project := &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22"}
+	if _, err := GenerateDockerfile(project, Options{RuntimeBase: RuntimeDistrolessStatic}); err == nil {
+		t.Error("Expected an error for a runtime base on a single-stage build")
+	}
+}
+
+func TestGenerateDockerfileCacheMounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  *detector.Project
+		contains []string
+		omits    []string
+	}{
+		{
+			name:    "Go",
+			project: &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22"},
+			contains: []string{
+				"RUN --mount=type=cache,target=/go/pkg/mod go mod download\n",
+				"RUN --mount=type=cache,target=/go/pkg/mod --mount=type=cache,target=/root/.cache/go-build CGO_ENABLED=0",
+			},
+		},
+		{
+			name:     "Go vendored",
+			project:  &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22", Vendored: true},
+			contains: []string{"RUN --mount=type=cache,target=/root/.cache/go-build CGO_ENABLED=0"},
+			omits:    []string{"target=/go/pkg/mod"},
+		},
+		{
+			name:     "npm",
+			project:  &detector.Project{Type: detector.NodeJS, Entrypoint: "index.js", PackageManager: detector.Npm},
+			contains: []string{"RUN --mount=type=cache,target=/root/.npm npm"},
+		},
+		{
+			name:     "pnpm",
+			project:  &detector.Project{Type: detector.NodeJS, Entrypoint: "index.js", PackageManager: detector.Pnpm},
+			contains: []string{"RUN --mount=type=cache,target=/root/.local/share/pnpm/store "},
+		},
+		{
+			name:     "pip",
+			project:  &detector.Project{Type: detector.Python, Entrypoint: "main.py", PackageManager: detector.Pip, LockFile: "requirements.txt"},
+			contains: []string{"RUN --mount=type=cache,target=/root/.cache/pip pip install -r requirements.txt\n"},
+			omits:    []string{"--no-cache-dir"},
+		},
+		{
+			name:    "uv",
+			project: &detector.Project{Type: detector.Python, Entrypoint: "main.py", PackageManager: detector.Uv, LockFile: "uv.lock"},
+			contains: []string{
+				"RUN --mount=type=cache,target=/root/.cache/pip --mount=type=cache,target=/root/.cache/uv pip install uv\n",
+				"--mount=type=cache,target=/root/.cache/uv UV_LINK_MODE=copy UV_PROJECT_ENVIRONMENT=/opt/venv uv sync",
+			},
+			omits: []string{"--no-cache-dir"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := GenerateDockerfile(tc.project, Options{MultiStage: true, CacheMounts: true})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, tc.contains, tc.omits)
+
+			content, err = GenerateDockerfile(tc.project, Options{MultiStage: true})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+			assertDockerfile(t, content, nil, []string{"--mount=type=cache"})
+		})
+	}
+}
+
+func TestGenerateGoDockerfileCrossCompile(t *testing.T) {
+	crossCompile := []string{
+		"FROM --platform=$BUILDPLATFORM golang:1.22-alpine AS go-build\n",
+		"ARG TARGETOS TARGETARCH\n",
+		"RUN CGO_ENABLED=0 GOOS=$TARGETOS GOARCH=$TARGETARCH go build",
+	}
+
+	tests := []struct {
+		name       string
+		multiStage bool
+		cgo        bool
+		contains   []string
+		omits      []string
+	}{
+		{
+			name:       "Multi-stage",
+			multiStage: true,
+			contains:   crossCompile,
+		},
+		{
+			name:       "cgo",
+			multiStage: true,
+			cgo:        true,
+			contains:   []string{"FROM golang:1.22-alpine AS go-build\n", "RUN CGO_ENABLED=1 go build"},
+			omits:      []string{"$BUILDPLATFORM", "TARGETOS"},
+		},
+		{
+			name:     "Single-stage",
+			contains: []string{"FROM golang:1.22-alpine\n", "RUN CGO_ENABLED=0 go build"},
+			omits:    []string{"$BUILDPLATFORM", "TARGETOS"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			project := &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22", CGO: tc.cgo}
+
+			content, err := GenerateDockerfile(project, Options{MultiStage: tc.multiStage, Platforms: []string{"linux/amd64", "linux/arm64"}})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, tc.contains, tc.omits)
+		})
+	}
+}
+
+func TestValidatePlatforms(t *testing.T) {
+	tests := []struct {
+		platform string
+		wantErr  bool
+	}{
+		{"linux/amd64", false},
+		{"linux/arm/v7", false},
+		{"linux", true},
+		{"linux/", true},
+		{"/arm64", true},
+		{"linux/arm/v7/extra", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.platform, func(t *testing.T) {
+			err := validatePlatforms([]string{"linux/amd64", tc.platform})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validatePlatforms(%q) error = %v, want error %v", tc.platform, err, tc.wantErr)
+			}
+		})
+	}
+
+	project := &detector.Project{Type: detector.Go, Entrypoint: "main.go", Version: "1.22"}
+	if _, err := GenerateDockerfile(project, Options{MultiStage: true, Platforms: []string{"arm64"}}); err == nil {
+		t.Error("Expected GenerateDockerfile to reject an invalid platform")
+	}
+}
+
+func TestGenerateGoDockerfileVersionVars(t *testing.T) {
+	project := &detector.Project{
+		Type:       detector.Go,
+		Entrypoint: "main.go",
+		Version:    "1.22",
+		VersionVars: []detector.VersionVar{
+			{Symbol: "main.version", Arg: "VERSION", Default: "dev"},
+			{Symbol: "example.com/app/internal/build.Commit", Arg: "COMMIT"},
+		},
+	}
+
+	for _, multiStage := range []bool{true, false} {
+		t.Run(fmt.Sprintf("multi-stage=%v", multiStage), func(t *testing.T) {
+			content, err := GenerateDockerfile(project, Options{MultiStage: multiStage})
+			if err != nil {
+				t.Fatalf("GenerateDockerfile failed: %v", err)
+			}
+
+			assertDockerfile(t, content, []string{
+				`-ldflags="-s -w -X 'main.version=${VERSION:-dev}' -X 'example.com/app/internal/build.Commit=${COMMIT:-}'"`,
+			}, nil)
+
+			// The build args have to be declared in the build stage to reach the go build command
+			_, buildStage, _ := strings.Cut(content, "COPY . .")
+			buildStage, _, _ = strings.Cut(buildStage, "go build")
+			assertDockerfile(t, buildStage, []string{"ARG VERSION\nARG COMMIT\nARG DATE\n"}, nil)
+		})
+	}
+
+	project.VersionVars = nil
+	content, err := GenerateDockerfile(project, Options{MultiStage: true})
+	if err != nil {
+		t.Fatalf("GenerateDockerfile failed: %v", err)
+	}
+	assertDockerfile(t, content, []string{`-ldflags="-s -w" `}, []string{"-X "})
+
+	_, buildStage, _ := strings.Cut(content, "COPY . .")
+	buildStage, _, _ = strings.Cut(buildStage, "go build")
+	assertDockerfile(t, buildStage, nil, []string{"ARG VERSION"})
+}
@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+)
+
+// commonIgnores keeps version control, editor and Docker files out of every build context
+var commonIgnores = []string{
+	".git",
+	".gitignore",
+	".dockerignore",
+	"**/*.dockerignore",
+	"Dockerfile*",
+	"docker-compose*.yml",
+	"compose*.yaml",
+	".idea",
+	".vscode",
+	"**/.DS_Store",
+	"**/*.swp",
+}
+
+// languageIgnores are the dependency directories, caches and build output of each project type.
+// Dependencies are installed inside the image, so copying the local ones only slows the build down.
+var languageIgnores = map[detector.ProjectType][]string{
+	detector.Go: {
+		"bin",
+		"**/*.test",
+		"**/*.out",
+		"coverage.*",
+	},
+	detector.NodeJS: {
+		"**/node_modules",
+		"**/npm-debug.log*",
+		"**/yarn-debug.log*",
+		"**/yarn-error.log*",
+		"**/.pnpm-store",
+		".next",
+		".nuxt",
+		"coverage",
+	},
+	detector.Python: {
+		"**/__pycache__",
+		"**/*.py[cod]",
+		".venv",
+		"venv",
+		"**/*.egg-info",
+		".pytest_cache",
+		".mypy_cache",
+		".ruff_cache",
+		".tox",
+		"build",
+		"dist",
+	},
+}
+
+// secretIgnores go last so neither the language defaults nor .gitignore negations can re-include them
+var secretIgnores = []string{
+	"**/.env",
+	"**/.env.*",
+	"!**/.env.example",
+	"**/*.pem",
+	"**/*.key",
+	"**/*.p12",
+	"**/*.pfx",
+	"**/id_rsa*",
+	"**/id_ecdsa*",
+	"**/id_ed25519*",
+	"**/.netrc",
+	"**/.pypirc",
+	"**/.aws",
+	"**/.ssh",
+	"**/*.tfstate*",
+	"**/*.tfvars",
+}
+
+// GenerateDockerignore creates a .dockerignore for the project, seeded from the contents of its .gitignore
+func GenerateDockerignore(project *detector.Project, gitignore string) (string, error) {
+	if project == nil {
+		return "", fmt.Errorf("project cannot be nil")
+	}
+
+	var buf strings.Builder
+
+	writeSection := func(comment string, patterns []string) {
+		if len(patterns) == 0 {
+			return
+		}
+		buf.WriteString(fmt.Sprintf("# %s\n", comment))
+		for _, pattern := range patterns {
+			buf.WriteString(pattern + "\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	writeSection("Version control, editor and Docker files", commonIgnores)
+	writeSection(fmt.Sprintf("Dependencies, caches and build output of %s projects", project.Type), languageIgnores[project.Type])
+	writeSection("From .gitignore", convertGitignore(gitignore))
+
+	// A vendored build reads its modules from vendor/, even when .gitignore lists it
+	if project.Type == detector.Go && project.Vendored {
+		writeSection("Vendored modules are part of the build", []string{"!vendor"})
+	}
+
+	writeSection("Secrets and credentials", secretIgnores)
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// convertGitignore rewrites .gitignore patterns into .dockerignore syntax. Git matches patterns without
+// a slash at any depth and anchors the others to the root, while Docker anchors every pattern, so
+// unanchored patterns get a **/ prefix.
+func convertGitignore(gitignore string) []string {
+	var patterns []string
+	for _, line := range strings.Split(gitignore, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		negate := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(line, "!")
+
+		// Docker matches directories by name, so a trailing slash only narrows what git matches
+		pattern := strings.TrimSuffix(line, "/")
+		if pattern == "" {
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+		} else if !strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "**") {
+			pattern = "**/" + pattern
+		}
+
+		if negate {
+			pattern = "!" + pattern
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+)
+
+func TestConvertGitignore(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitignore string
+		expected  []string
+	}{
+		{name: "Unanchored name", gitignore: "*.log\n", expected: []string{"**/*.log"}},
+		{name: "Anchored to the root", gitignore: "/build\n", expected: []string{"build"}},
+		{name: "Path with a slash", gitignore: "docs/_site\n", expected: []string{"docs/_site"}},
+		{name: "Directory only", gitignore: "tmp/\n", expected: []string{"**/tmp"}},
+		{name: "Anchored directory", gitignore: "/out/\n", expected: []string{"out"}},
+		{name: "Already recursive", gitignore: "**/cache\n", expected: []string{"**/cache"}},
+		{name: "Negation", gitignore: "*.env\n!sample.env\n", expected: []string{"**/*.env", "!**/sample.env"}},
+		{name: "Anchored negation", gitignore: "!/keep\n", expected: []string{"!keep"}},
+		{name: "Comments and blank lines", gitignore: "# build output\n\n   \ncoverage\n", expected: []string{"**/coverage"}},
+		{name: "Lone slash", gitignore: "/\n", expected: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := convertGitignore(tc.gitignore)
+			if strings.Join(got, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("Expected patterns %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerateDockerignore(t *testing.T) {
+	tests := []struct {
+		name      string
+		project   *detector.Project
+		gitignore string
+		contains  []string
+		omits     []string
+	}{
+		{
+			name:      "Node project with a .gitignore",
+			project:   &detector.Project{Type: detector.NodeJS},
+			gitignore: "node_modules/\n!.env.example\n",
+			contains:  []string{".git\n", "**/node_modules\n", "# From .gitignore\n**/node_modules\n!**/.env.example\n"},
+			omits:     []string{"**/__pycache__", "!vendor"},
+		},
+		{
+			name:     "Python project",
+			project:  &detector.Project{Type: detector.Python},
+			contains: []string{"**/__pycache__\n", ".venv\n"},
+			omits:    []string{"# From .gitignore", "**/node_modules"},
+		},
+		{
+			name:      "Vendored Go project",
+			project:   &detector.Project{Type: detector.Go, Vendored: true},
+			gitignore: "vendor/\n",
+			contains:  []string{"# From .gitignore\n**/vendor\n", "# Vendored modules are part of the build\n!vendor\n"},
+		},
+		{
+			name:     "Go project with modules downloaded in the build",
+			project:  &detector.Project{Type: detector.Go},
+			contains: []string{"**/*.test\n"},
+			omits:    []string{"!vendor"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := GenerateDockerignore(tc.project, tc.gitignore)
+			if err != nil {
+				t.Fatalf("GenerateDockerignore failed: %v", err)
+			}
+
+			for _, expected := range tc.contains {
+				if !strings.Contains(content, expected) {
+					t.Errorf("Expected .dockerignore to contain %q, got:\n%s", expected, content)
+				}
+			}
+			for _, unexpected := range tc.omits {
+				if strings.Contains(content, unexpected) {
+					t.Errorf("Expected .dockerignore without %q, got:\n%s", unexpected, content)
+				}
+			}
+
+			// Secrets go last, so no earlier negation can bring them back
+			secrets := strings.Index(content, "# Secrets and credentials\n")
+			if secrets == -1 || strings.Contains(content[secrets:], "\n# ") {
+				t.Errorf("Expected the secrets section last, got:\n%s", content)
+			}
+			if !strings.HasSuffix(content, secretIgnores[len(secretIgnores)-1]+"\n") {
+				t.Errorf("Expected the file to end with the secrets, got:\n%s", content)
+			}
+		})
+	}
+
+	if _, err := GenerateDockerignore(nil, ""); err == nil {
+		t.Error("Expected an error for a nil project")
+	}
+}
@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+)
+
+// GenerateEnvExample creates a .env.example listing the environment variables the project reads with
+// their defaults, so new developers know what to set in their .env
+func GenerateEnvExample(project *detector.Project) (string, error) {
+	if project == nil {
+		return "", fmt.Errorf("project cannot be nil")
+	}
+
+	if len(project.EnvVars) == 0 {
+		return "", fmt.Errorf("no environment variables found")
+	}
+
+	var buf strings.Builder
+	buf.WriteString("# Environment variables read by the project. Copy this file to .env and fill in the\n")
+	buf.WriteString("# values without defaults; docker compose passes them to the app container.\n")
+
+	for _, envVar := range project.EnvVars {
+		value := envVar.Default
+		if envVar.Name == "PORT" && project.Port > 0 {
+			value = strconv.Itoa(project.Port)
+		}
+
+		buf.WriteString(fmt.Sprintf("\n# %s\n", envVar.Source))
+		buf.WriteString(fmt.Sprintf("%s=%s\n", envVar.Name, dotenvValue(value)))
+	}
+
+	return buf.String(), nil
+}
+
+// dotenvValue quotes values that dotenv parsers would otherwise cut at whitespace or a comment
+func dotenvValue(value string) string {
+	if strings.ContainsAny(value, " \t#'\"") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Babatunde50/dockergen/internal/detector"
+)
+
+func TestGenerateEnvExample(t *testing.T) {
+	project := &detector.Project{
+		Type: detector.NodeJS,
+		Port: 3000,
+		EnvVars: []detector.EnvVar{
+			{Name: "API_KEY", Source: ".env"},
+			{Name: "GREETING", Default: "hello world", Source: "index.js:4"},
+			{Name: "PORT", Source: "index.js:2"},
+		},
+	}
+
+	content, err := GenerateEnvExample(project)
+	if err != nil {
+		t.Fatalf("GenerateEnvExample failed: %v", err)
+	}
+
+	for _, expected := range []string{"# .env\nAPI_KEY=\n", "# index.js:4\nGREETING=\"hello world\"\n", "PORT=3000\n"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected .env.example to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	if _, err := GenerateEnvExample(&detector.Project{Type: detector.Go}); err == nil {
+		t.Error("Expected an error for a project without environment variables")
+	}
+}
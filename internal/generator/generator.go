@@ -1,5 +1,64 @@
 package generator
 
+import "github.com/Babatunde50/dockergen/internal/imagelock"
+
+// Options controls how Dockerfiles and docker-compose files are generated
+type Options struct {
+	MultiStage bool
+	Binary     string // Generate the Dockerfile for this binary only
+	PerBinary  bool   // Build every binary from its own Dockerfile.<name> instead of a target stage
+
+	PrivateModules string // How go mod download authenticates to private repositories: "ssh", "netrc" or empty
+	RuntimeBase    string // Base image of the Go runtime stage, one of the RuntimeBase constants; empty means alpine
+
+	ImageLock   *imagelock.Lock // Pins every base image to the digest recorded here when set
+	CacheMounts bool            // Keep dependency and build caches in BuildKit cache mounts between builds
+	Platforms   []string        // Target platforms such as linux/amd64 for multi-architecture builds
+	Publish     []string        // Compose port mappings; one for the detected port replaces the default
+	ComposeSpec string          // Compose file format, one of the ComposeSpec constants; empty means ComposeSpecCompose
+}
+
+// Compose file formats
+const (
+	// ComposeSpecCompose is the Compose Specification, which has no version key
+	ComposeSpecCompose = "compose"
+
+	// ComposeSpecV2 is the legacy 2.4 file format for docker-compose v1 without Swarm
+	ComposeSpecV2 = "v2"
+
+	// ComposeSpecV3 is the legacy 3.8 file format shared with docker stack deploy
+	ComposeSpecV3 = "v3"
+)
+
+const (
+	// PrivateModulesSSH forwards the host's SSH agent into go mod download
+	PrivateModulesSSH = "ssh"
+
+	// PrivateModulesNetrc mounts a .netrc build secret with git credentials into go mod download
+	PrivateModulesNetrc = "netrc"
+)
+
+// Runtime base images for the Go multi-stage build
+const (
+	// RuntimeAlpine is Alpine Linux with a shell, apk and musl libc
+	RuntimeAlpine = "alpine"
+
+	// RuntimeDistrolessStatic has CA certificates and time zones but no libc, for static binaries
+	RuntimeDistrolessStatic = "distroless-static"
+
+	// RuntimeDistrolessBase adds glibc to distroless static, so it can run cgo binaries
+	RuntimeDistrolessBase = "distroless-base"
+
+	// RuntimeScratch is the empty image, for static binaries
+	RuntimeScratch = "scratch"
+
+	// RuntimeChainguard is Chainguard's static image, or its glibc image for cgo binaries
+	RuntimeChainguard = "chainguard"
+)
+
+// RuntimeBases lists the supported runtime base images
+var RuntimeBases = []string{RuntimeAlpine, RuntimeDistrolessStatic, RuntimeDistrolessBase, RuntimeScratch, RuntimeChainguard}
+
 type DockerfileTemplate struct {
 	BuildCmd      string
 	RunCmd        string
@@ -8,6 +67,48 @@ type DockerfileTemplate struct {
 	UseMultiStage bool
 	BinaryName    string
 	Version       string
+
+	DependencyFiles string // Manifests and lockfiles copied before installing dependencies
+	ToolInstallCmd  string // Installs the package manager itself
+	InstallCmd      string
+	ProdInstallCmd  string
+	Cmd             string // Exec-form CMD, e.g. ["node", "index.js"]
+
+	Targets []Target // One runtime stage per binary of a multi-binary project
+
+	ModuleCopies []string // COPY arguments for go.work, go.mod and go.sum files
+	BuildDir     string   // Workspace module the Go build runs in, relative to the build context
+	CGO          bool     // Build with cgo, which needs a C toolchain in the build stage
+	Vendored     bool     // Build from vendor/ without downloading modules
+
+	PrivateModules string // See Options.PrivateModules
+	DownloadMounts string // RUN --mount flags for go mod download
+	BuildMounts    string // RUN --mount flags for go build
+	InstallMounts  string // RUN --mount flags for the Node.js and Python install commands
+
+	BuildImage    string // Go build stage image
+	DebianBuilder bool   // The build stage is Debian based and already ships gcc, git and ssh
+	RuntimeBase   string // See Options.RuntimeBase
+	RuntimeImage  string // Go runtime stage image
+	RuntimeUser   string // Non-root user the runtime stage runs as
+	CrossCompile  bool   // Build on the native platform and cross-compile for the target platform
+
+	StampVersion  bool    // The Go build sets version variables from the VERSION, COMMIT and DATE args
+	Labels        []Label // Static OCI image labels
+	ImageVersion  string  // Quoted default of the VERSION build arg
+	ImageRevision string  // Quoted default of the COMMIT build arg
+}
+
+// Label is an image label whose value is already quoted for the Dockerfile
+type Label struct {
+	Key   string
+	Value string
+}
+
+// Target is a named runtime stage for one binary of a multi-binary project
+type Target struct {
+	Name     string
+	BuildCmd string
 }
 
 type DockerComposeTemplate struct {
@@ -20,124 +121,138 @@ type DockerComposeTemplate struct {
 	Secrets  []Secret
 }
 
+// The compose types map to the Compose file keys through their yaml tags. Named entries such as services
+// and volumes are written as mappings keyed by Name, see DockerComposeTemplate.MarshalYAML.
+
 type Service struct {
-	Name          string
-	Image         string
-	Build         Build
-	Ports         []string
-	Environment   map[string]string
-	EnvFile       []string
-	DependsOn     []string
-	Volumes       []string
-	Networks      []string
-	Restart       string
-	HealthCheck   HealthCheck
-	Deploy        Deploy
-	Labels        map[string]string
-	Command       string
-	Entrypoint    string
-	User          string
-	WorkingDir    string
-	ContainerName string
-	ReadOnly      bool
+	Name            string            `yaml:"-"`
+	ContainerName   string            `yaml:"container_name,omitempty"`
+	Image           string            `yaml:"image,omitempty"`
+	Build           Build             `yaml:"build,omitempty"`
+	Restart         string            `yaml:"restart,omitempty"`
+	Ports           []string          `yaml:"ports,omitempty"`
+	Environment     map[string]string `yaml:"environment,omitempty"`
+	PassEnvironment []string          `yaml:"-"` // Environment keys without a value, set only if the shell or .env sets them
+	EnvFile         []string          `yaml:"env_file,omitempty"`
+	DependsOn       []Dependency      `yaml:"depends_on,omitempty"`
+	Volumes         []string          `yaml:"volumes,omitempty"`
+	Networks        []string          `yaml:"networks,omitempty"`
+	HealthCheck     HealthCheck       `yaml:"healthcheck,omitempty"`
+	Deploy          Deploy            `yaml:"deploy,omitempty"`
+	Labels          map[string]string `yaml:"labels,omitempty"`
+	Command         string            `yaml:"command,omitempty"`
+	Entrypoint      string            `yaml:"entrypoint,omitempty"`
+	User            string            `yaml:"user,omitempty"`
+	WorkingDir      string            `yaml:"working_dir,omitempty"`
+	ReadOnly        bool              `yaml:"read_only,omitempty"`
+}
+
+// Dependency is a depends_on entry. Without conditions the services are written as a list, otherwise as
+// a mapping with the condition of each, see Service.MarshalYAML.
+type Dependency struct {
+	Service   string `yaml:"-"`
+	Condition string `yaml:"condition,omitempty"` // e.g. "service_healthy"
 }
 
 type Build struct {
-	Context    string
-	Dockerfile string
-	Args       map[string]string
-	Target     string // For multi-stage builds
-	CacheFrom  []string
+	Context    string            `yaml:"context,omitempty"`
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+	Target     string            `yaml:"target,omitempty"` // For multi-stage builds
+	CacheFrom  []string          `yaml:"cache_from,omitempty"`
+	Platforms  []string          `yaml:"platforms,omitempty"`
+	SSH        []string          `yaml:"ssh,omitempty"`     // SSH agent sockets or keys exposed to RUN --mount=type=ssh
+	Secrets    []string          `yaml:"secrets,omitempty"` // Top-level secrets exposed to RUN --mount=type=secret
 }
 
 type Network struct {
-	Name       string
-	Driver     string // e.g., "bridge", "overlay"
-	External   bool
-	Attachable bool
-	IPAM       IPAM
-	Labels     map[string]string
+	Name       string            `yaml:"-"`
+	Driver     string            `yaml:"driver,omitempty"` // e.g., "bridge", "overlay"
+	External   bool              `yaml:"external,omitempty"`
+	Attachable bool              `yaml:"attachable,omitempty"`
+	IPAM       IPAM              `yaml:"ipam,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
 }
 
 type IPAM struct {
-	Driver string
-	Config []IPAMConfig
+	Driver string       `yaml:"driver,omitempty"`
+	Config []IPAMConfig `yaml:"config,omitempty"`
 }
 
 type IPAMConfig struct {
-	Subnet     string
-	Gateway    string
-	IPRange    string
-	AuxAddress map[string]string
+	Subnet     string            `yaml:"subnet,omitempty"`
+	Gateway    string            `yaml:"gateway,omitempty"`
+	IPRange    string            `yaml:"ip_range,omitempty"`
+	AuxAddress map[string]string `yaml:"aux_addresses,omitempty"`
 }
 
 type Volume struct {
-	Name     string
-	Driver   string
-	External bool
-	Labels   map[string]string
-	Options  map[string]string
+	Name     string            `yaml:"-"`
+	Driver   string            `yaml:"driver,omitempty"`
+	External bool              `yaml:"external,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Options  map[string]string `yaml:"driver_opts,omitempty"`
 }
 
 type Config struct {
-	Name     string
-	File     string
-	External bool
+	Name     string `yaml:"-"`
+	File     string `yaml:"file,omitempty"`
+	External bool   `yaml:"external,omitempty"`
 }
 
 type Secret struct {
-	Name     string
-	File     string
-	External bool
+	Name     string `yaml:"-"`
+	File     string `yaml:"file,omitempty"`
+	External bool   `yaml:"external,omitempty"`
 }
 
 type HealthCheck struct {
-	Test        []string
-	Interval    string
-	Timeout     string
-	Retries     int
-	StartPeriod string
+	Test        []string `yaml:"test,omitempty"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
 }
 
 type Deploy struct {
-	Mode         string
-	Replicas     int
-	Resources    Resources
-	UpdateConfig UpdateConfig
-	Placement    Placement
+	Mode         string       `yaml:"mode,omitempty"`
+	Replicas     int          `yaml:"replicas,omitempty"`
+	Resources    Resources    `yaml:"resources,omitempty"`
+	UpdateConfig UpdateConfig `yaml:"update_config,omitempty"`
+	Placement    Placement    `yaml:"placement,omitempty"`
 }
 
 type Resources struct {
-	Limits       ResourceSpec
-	Reservations ResourceSpec
+	Limits       ResourceSpec `yaml:"limits,omitempty"`
+	Reservations ResourceSpec `yaml:"reservations,omitempty"`
 }
 
 type ResourceSpec struct {
-	CPUs    string
-	Memory  string
-	Devices []DeviceSpec
+	CPUs    string       `yaml:"cpus,omitempty"`
+	Memory  string       `yaml:"memory,omitempty"`
+	Devices []DeviceSpec `yaml:"devices,omitempty"`
 }
 
 type DeviceSpec struct {
-	Capabilities []string
-	Count        int
-	Device       string
-	Driver       string
+	Capabilities []string `yaml:"capabilities,omitempty"`
+	Count        int      `yaml:"count,omitempty"`
+	DeviceIDs    []string `yaml:"device_ids,omitempty"`
+	Driver       string   `yaml:"driver,omitempty"`
 }
 
 type UpdateConfig struct {
-	Parallelism     int
-	Delay           string
-	FailureAction   string
-	MaxFailureRatio string
-	Order           string
+	Parallelism     int    `yaml:"parallelism,omitempty"`
+	Delay           string `yaml:"delay,omitempty"`
+	FailureAction   string `yaml:"failure_action,omitempty"`
+	MaxFailureRatio string `yaml:"max_failure_ratio,omitempty"`
+	Order           string `yaml:"order,omitempty"`
 }
 
 type Placement struct {
-	Constraints []string
-	Preferences []PlacementPreference
+	Constraints []string              `yaml:"constraints,omitempty"`
+	Preferences []PlacementPreference `yaml:"preferences,omitempty"`
 }
 
 type PlacementPreference struct {
-	Spread string
+	Spread string `yaml:"spread,omitempty"`
 }
@@ -1,28 +1,99 @@
 package generator
 
+// Templates shared by every Dockerfile: the build metadata args, declared before the first stage so CI
+// can override them with --build-arg, and the OCI labels of the final image. The labels come after the
+// last RUN of the final stage, since redeclaring COMMIT and DATE invalidates the cache of every RUN after
+// them and they change with each build.
+const metadataTemplates = `{{define "args"}}
+# Build metadata, COMMIT defaults to the commit checked out when this file was generated;
+# pass COMMIT and DATE with --build-arg in CI to label the commit actually built
+ARG VERSION={{.ImageVersion}}
+ARG COMMIT={{.ImageRevision}}
+ARG DATE
+{{end}}
+
+{{define "labels"}}
+# Image metadata following the OCI image annotations
+ARG VERSION
+ARG COMMIT
+ARG DATE
+{{range .Labels}}LABEL {{.Key}}={{.Value}}
+{{end}}LABEL org.opencontainers.image.version="$VERSION"
+LABEL org.opencontainers.image.revision="$COMMIT"
+LABEL org.opencontainers.image.created="$DATE"
+{{end}}`
+
 // Go Dockerfile template
 const goDockerfileTemplate = `# syntax=docker/dockerfile:1
-
+{{template "args" .}}
 {{if .UseMultiStage}}
 # === Multi-stage build ===
 
-# Build stage
-FROM golang:{{.Version}}-alpine AS build
+# Build stage, running on the build machine's platform when cross-compiling
+FROM {{if .CrossCompile}}--platform=$BUILDPLATFORM {{end}}{{.BuildImage}} AS go-build
 WORKDIR /app
 
+{{if and .CGO (not .DebianBuilder)}}
+# Install the C toolchain cgo needs, the musl libc matches the Alpine runtime image
+RUN apk add --no-cache gcc musl-dev
+{{end}}
+
+{{if eq .RuntimeBase "scratch"}}
+# scratch is empty, so the runtime stage copies the CA certificates and time zones from here
+RUN apk add --no-cache ca-certificates tzdata
+{{end}}
+
+{{if .Vendored}}
+# Dependencies are vendored, so the build needs no module downloads
+{{else}}
+# Pass GOPROXY and GOPRIVATE as build args to use a module mirror or skip it for private modules
+ARG GOPROXY
+ARG GOPRIVATE
+
+{{if eq .PrivateModules "ssh"}}
+# Fetch private modules over SSH, build with: docker build --ssh default .
+ARG GIT_HOST=github.com
+{{if not .DebianBuilder}}RUN apk add --no-cache git openssh-client
+{{end}}RUN mkdir -p -m 0700 /root/.ssh \
+    && ssh-keyscan "$GIT_HOST" >> /root/.ssh/known_hosts \
+    && git config --global url."ssh://git@${GIT_HOST}/".insteadOf "https://${GIT_HOST}/"
+{{else if eq .PrivateModules "netrc"}}
+# Fetch private modules with .netrc credentials, build with: docker build --secret id=netrc,src=$HOME/.netrc .
+{{if not .DebianBuilder}}RUN apk add --no-cache git{{end}}
+{{end}}
+
 # Copy go.mod and go.sum files first and download dependencies
-COPY go.mod go.sum* ./
-RUN go mod download
+{{range .ModuleCopies}}COPY {{.}}
+{{end}}RUN {{.DownloadMounts}}go mod download
+{{end}}
 
 # Copy source code
 COPY . .
 
-# Build the application with optimizations for smaller binary size
-RUN {{.BuildCmd}}
+{{if .BuildDir}}
+# Build from the selected workspace module
+WORKDIR /app/{{.BuildDir}}
+{{end}}
 
-# Runtime stage with a minimal Alpine image
-FROM alpine:latest
+{{if .CrossCompile}}
+# Cross-compile for the platform being built, buildx sets these for each --platform
+ARG TARGETOS TARGETARCH
+{{end}}
+
+{{if .StampVersion}}
+# Stamp the build metadata into the binary's version variables
+ARG VERSION
+ARG COMMIT
+ARG DATE
+{{end}}
+
+# Build the application with optimizations for smaller binary size
+{{if .Targets}}{{$mounts := .BuildMounts}}{{range .Targets}}RUN {{$mounts}}{{.BuildCmd}}
+{{end}}{{else}}RUN {{.BuildMounts}}{{.BuildCmd}}{{end}}
 
+# Runtime stage with a minimal {{.RuntimeBase}} image
+FROM {{.RuntimeImage}}{{if .Targets}} AS go-runtime{{end}}
+{{if eq .RuntimeBase "alpine"}}
 # Install necessary runtime dependencies
 RUN apk --no-cache add \
     ca-certificates \
@@ -33,15 +104,37 @@ RUN addgroup -S appgroup && adduser -S appuser -G appgroup
 
 # Create app directory and set permissions
 RUN mkdir -p /app && chown -R appuser:appgroup /app
+{{else if eq .RuntimeBase "scratch"}}
+# Copy the CA certificates and time zone database the Go runtime looks for
+COPY --from=go-build /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/
+COPY --from=go-build /usr/share/zoneinfo /usr/share/zoneinfo
+{{else}}
+# The image ships CA certificates and time zones, and has no shell to create a user with
+{{end}}
 
 # Set the working directory
 WORKDIR /app
 
-# Copy the binary from the build stage
-COPY --from=build {{.Entrypoint}} /app/
+{{if .Targets}}{{template "labels" .}}
+# Switch to non-root user for security
+USER {{.RuntimeUser}}
 
+{{if .Port}}
+# Expose the application port
+EXPOSE {{.Port}}
+{{end}}
+{{range .Targets}}
+# Runtime target for {{.Name}}, build it with --target {{.Name}}
+FROM go-runtime AS {{.Name}}
+COPY --from=go-build /app/{{.Name}} /app/
+ENTRYPOINT ["/app/{{.Name}}"]
+{{end}}
+{{else}}
+# Copy the binary from the build stage
+COPY --from=go-build {{.Entrypoint}} /app/
+{{template "labels" .}}
 # Switch to non-root user for security
-USER appuser
+USER {{.RuntimeUser}}
 
 {{if .Port}}
 # Expose the application port
@@ -50,24 +143,229 @@ EXPOSE {{.Port}}
 
 # Run the application
 ENTRYPOINT ["/app/{{.BinaryName}}"]
+{{end}}
 
 {{else}}
 # === Single-stage build ===
 
-FROM golang:{{.Version}}-alpine
+FROM {{.BuildImage}}{{if .Targets}} AS go-build{{end}}
 WORKDIR /app
 
-COPY go.mod go.sum* ./
-RUN go mod download
+{{if .CGO}}
+RUN apk add --no-cache gcc musl-dev
+{{end}}
+
+{{if not .Vendored}}
+ARG GOPROXY
+ARG GOPRIVATE
+
+{{if eq .PrivateModules "ssh"}}
+ARG GIT_HOST=github.com
+RUN apk add --no-cache git openssh-client
+RUN mkdir -p -m 0700 /root/.ssh \
+    && ssh-keyscan "$GIT_HOST" >> /root/.ssh/known_hosts \
+    && git config --global url."ssh://git@${GIT_HOST}/".insteadOf "https://${GIT_HOST}/"
+{{else if eq .PrivateModules "netrc"}}
+RUN apk add --no-cache git
+{{end}}
+
+{{range .ModuleCopies}}COPY {{.}}
+{{end}}RUN {{.DownloadMounts}}go mod download
+{{end}}
 
 COPY . .
 
-RUN {{.BuildCmd}}
+{{if .BuildDir}}
+WORKDIR /app/{{.BuildDir}}
+{{end}}
 
+{{if .StampVersion}}
+ARG VERSION
+ARG COMMIT
+ARG DATE
+{{end}}
+
+{{if .Targets}}{{$mounts := .BuildMounts}}{{range .Targets}}RUN {{$mounts}}{{.BuildCmd}}
+{{end}}{{else}}RUN {{.BuildMounts}}{{.BuildCmd}}{{end}}
+{{template "labels" .}}
 {{if .Port}}
 EXPOSE {{.Port}}
 {{end}}
 
+{{if .Targets}}{{range .Targets}}
+FROM go-build AS {{.Name}}
+ENTRYPOINT ["/app/{{.Name}}"]
+{{end}}{{else}}
 ENTRYPOINT ["{{.RunCmd}}"]
+{{end}}
+
+{{end}}`
+
+// Node.js Dockerfile template
+const nodeDockerfileTemplate = `# syntax=docker/dockerfile:1
+{{template "args" .}}
+{{if .UseMultiStage}}
+# === Multi-stage build ===
+
+# Dependencies stage
+FROM node:{{.Version}}-alpine AS deps
+WORKDIR /app
+
+# Copy the manifest and lockfile first so dependencies are cached until they change
+COPY {{.DependencyFiles}} ./
+RUN {{.InstallMounts}}{{.InstallCmd}}
+
+# Build stage
+FROM node:{{.Version}}-alpine AS build
+WORKDIR /app
+
+COPY --from=deps /app/node_modules ./node_modules
+COPY . .
+
+# Run the build script if there is one, then drop the development dependencies
+RUN {{.BuildCmd}} && rm -rf node_modules
+
+# Runtime stage
+FROM node:{{.Version}}-alpine
+ENV NODE_ENV=production
+WORKDIR /app
+
+# Install production dependencies only
+COPY {{.DependencyFiles}} ./
+RUN {{.InstallMounts}}{{.ProdInstallCmd}}
+
+# Copy the application from the build stage
+COPY --from=build --chown=node:node /app ./
+{{template "labels" .}}
+# Switch to the non-root user shipped with the official Node.js image
+USER node
+
+{{if .Port}}
+# Expose the application port
+EXPOSE {{.Port}}
+{{end}}
+
+# Run the application
+CMD {{.Cmd}}
+
+{{else}}
+# === Single-stage build ===
+
+FROM node:{{.Version}}-alpine
+WORKDIR /app
+
+COPY {{.DependencyFiles}} ./
+RUN {{.InstallMounts}}{{.InstallCmd}}
+
+COPY . .
+
+RUN {{.BuildCmd}}
+{{template "labels" .}}
+ENV NODE_ENV=production
+
+USER node
+
+{{if .Port}}
+EXPOSE {{.Port}}
+{{end}}
+
+CMD {{.Cmd}}
+
+{{end}}`
+
+// Python Dockerfile template
+const pythonDockerfileTemplate = `# syntax=docker/dockerfile:1
+{{template "args" .}}
+{{if .UseMultiStage}}
+# === Multi-stage build ===
+
+# Build stage
+FROM python:{{.Version}}-slim AS build
+WORKDIR /app
+
+# Install the toolchain needed to build wheels for native extensions
+RUN apt-get update \
+    && apt-get install -y --no-install-recommends build-essential \
+    && rm -rf /var/lib/apt/lists/*
+
+{{if .ToolInstallCmd}}
+# Install the package manager outside of the application's virtual environment
+RUN {{.InstallMounts}}{{.ToolInstallCmd}}
+{{end}}
+
+# Build the dependencies into a virtual environment that is copied into the runtime stage
+RUN python -m venv /opt/venv
+ENV PATH="/opt/venv/bin:$PATH"
+{{if .DependencyFiles}}
+# Copy the dependency manifests first so this layer is cached until they change
+COPY {{.DependencyFiles}} ./
+RUN {{.InstallMounts}}{{.InstallCmd}}
+{{else if .InstallCmd}}
+COPY . .
+RUN {{.InstallMounts}}{{.InstallCmd}}
+{{end}}
+# Runtime stage with a slim Python image
+FROM python:{{.Version}}-slim
+ENV PYTHONDONTWRITEBYTECODE=1 \
+    PYTHONUNBUFFERED=1 \
+    PATH="/opt/venv/bin:$PATH"
+
+# Create a non-root user to run the application
+RUN groupadd --system appgroup && useradd --system --gid appgroup --no-create-home appuser
+
+# Set the working directory
+WORKDIR /app
+
+# Copy the virtual environment from the build stage, then the source code
+COPY --from=build /opt/venv /opt/venv
+COPY . .
+{{template "labels" .}}
+# Switch to non-root user for security
+USER appuser
+
+{{if .Port}}
+# Expose the application port
+EXPOSE {{.Port}}
+{{end}}
+
+# Run the application
+CMD {{.Cmd}}
+
+{{else}}
+# === Single-stage build ===
+
+FROM python:{{.Version}}-slim
+ENV PYTHONDONTWRITEBYTECODE=1 \
+    PYTHONUNBUFFERED=1
+
+WORKDIR /app
+
+{{if .ToolInstallCmd}}
+RUN {{.InstallMounts}}{{.ToolInstallCmd}}
+{{end}}
+
+RUN python -m venv /opt/venv
+ENV PATH="/opt/venv/bin:$PATH"
+
+{{if .DependencyFiles}}
+COPY {{.DependencyFiles}} ./
+RUN {{.InstallMounts}}{{.InstallCmd}}
+
+COPY . .
+{{else}}
+COPY . .
+{{if .InstallCmd}}
+RUN {{.InstallMounts}}{{.InstallCmd}}
+{{end}}{{end}}
+
+RUN groupadd --system appgroup && useradd --system --gid appgroup --no-create-home appuser
+{{template "labels" .}}
+USER appuser
+
+{{if .Port}}
+EXPOSE {{.Port}}
+{{end}}
+
+CMD {{.Cmd}}
 
 {{end}}`
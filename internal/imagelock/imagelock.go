@@ -0,0 +1,151 @@
+package imagelock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the lock file checked in next to the generated Dockerfiles
+const FileName = "dockergen.lock.json"
+
+// Lock maps base image references such as golang:1.22-alpine to their sha256 manifest digests
+type Lock struct {
+	Images map[string]string `json:"images"`
+}
+
+// Load reads the lock file in dir
+func Load(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s not found, run dockergen lock to create it", FileName)
+		}
+		return nil, fmt.Errorf("failed to read %s: %v", FileName, err)
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", FileName, err)
+	}
+
+	if lock.Images == nil {
+		lock.Images = map[string]string{}
+	}
+
+	return &lock, nil
+}
+
+// Save writes the lock file to dir. encoding/json sorts map keys, so the file diffs cleanly.
+func (l *Lock) Save(dir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", FileName, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", FileName, err)
+	}
+
+	return nil
+}
+
+// Pin rewrites every FROM line of dockerfile that names a registry image to tag@digest. It fails
+// listing every image missing from the lock, so one lock refresh fixes them all.
+func (l *Lock) Pin(dockerfile string) (string, error) {
+	lines := strings.Split(dockerfile, "\n")
+	stages := map[string]bool{}
+
+	var missing []string
+	for i, line := range lines {
+		from, ok := parseFrom(line, stages)
+		if !ok || !from.external {
+			continue
+		}
+
+		digest, ok := l.Images[from.ref]
+		if !ok {
+			missing = append(missing, from.ref)
+			continue
+		}
+
+		lines[i] = line[:from.start] + from.ref + "@" + digest + line[from.end:]
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no digest in %s for %s, run dockergen lock to refresh it", FileName, strings.Join(missing, ", "))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// References returns the registry images the FROM lines of dockerfile name, without any digest,
+// sorted and deduplicated
+func References(dockerfile string) []string {
+	stages := map[string]bool{}
+	seen := map[string]bool{}
+
+	var refs []string
+	for _, line := range strings.Split(dockerfile, "\n") {
+		from, ok := parseFrom(line, stages)
+		if !ok || !from.external || seen[from.ref] {
+			continue
+		}
+		seen[from.ref] = true
+		refs = append(refs, from.ref)
+	}
+
+	sort.Strings(refs)
+	return refs
+}
+
+// fromLine is the image of a FROM instruction and where it sits in the line
+type fromLine struct {
+	ref        string // Image reference without digest
+	start, end int    // Byte range of the image, including any digest, in the line
+	external   bool   // Pulled from a registry rather than scratch, an earlier stage or a build arg
+}
+
+// parseFrom parses a FROM instruction, recording its stage name in stages so later lines that build
+// on the stage are not mistaken for registry images
+func parseFrom(line string, stages map[string]bool) (fromLine, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+		return fromLine{}, false
+	}
+
+	// Skip flags such as --platform=$BUILDPLATFORM
+	image := 1
+	for image < len(fields) && strings.HasPrefix(fields[image], "--") {
+		image++
+	}
+	if image == len(fields) {
+		return fromLine{}, false
+	}
+
+	name := fields[image]
+
+	// The image is the first occurrence of the field after the instruction and its flags
+	offset := 0
+	for _, flag := range fields[:image] {
+		offset = strings.Index(line[offset:], flag) + offset + len(flag)
+	}
+	start := strings.Index(line[offset:], name) + offset
+
+	ref, _, _ := strings.Cut(name, "@")
+	from := fromLine{
+		ref:   ref,
+		start: start,
+		end:   start + len(name),
+	}
+	from.external = ref != "scratch" && !stages[strings.ToLower(ref)] && !strings.Contains(ref, "$")
+
+	if image+2 < len(fields) && strings.EqualFold(fields[image+1], "AS") {
+		stages[strings.ToLower(fields[image+2])] = true
+	}
+
+	return from, true
+}
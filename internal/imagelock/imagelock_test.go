@@ -0,0 +1,124 @@
+package imagelock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testDockerfile = `# syntax=docker/dockerfile:1
+
+FROM golang:1.22-alpine AS build
+WORKDIR /app
+
+FROM --platform=$BUILDPLATFORM alpine:3.22@sha256:0000 AS runtime
+COPY --from=build /app/app /app/
+
+FROM runtime AS api
+FROM scratch
+FROM $BASE_IMAGE
+`
+
+func TestReferences(t *testing.T) {
+	expected := []string{"alpine:3.22", "golang:1.22-alpine"}
+
+	got := References(testDockerfile)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected references %v, got %v", expected, got)
+	}
+}
+
+func TestPin(t *testing.T) {
+	lock := &Lock{Images: map[string]string{
+		"golang:1.22-alpine": "sha256:1111",
+		"alpine:3.22":        "sha256:2222",
+	}}
+
+	pinned, err := lock.Pin(testDockerfile)
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	for _, line := range []string{
+		"FROM golang:1.22-alpine@sha256:1111 AS build",
+		"FROM --platform=$BUILDPLATFORM alpine:3.22@sha256:2222 AS runtime",
+		"FROM runtime AS api",
+		"FROM scratch",
+		"FROM $BASE_IMAGE",
+	} {
+		if !strings.Contains(pinned, line+"\n") {
+			t.Errorf("Expected pinned Dockerfile to contain %q, got:\n%s", line, pinned)
+		}
+	}
+
+	delete(lock.Images, "alpine:3.22")
+	if _, err := lock.Pin(testDockerfile); err == nil || !strings.Contains(err.Error(), "alpine:3.22") {
+		t.Errorf("Expected an error naming the missing image, got %v", err)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	const digest = "sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+
+	// A registry stand-in that requires an anonymous bearer token like Docker Hub does
+	var registry *httptest.Server
+	registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:library/alpine:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+		case r.Header.Get("Authorization") != "Bearer anonymous":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:library/alpine:pull"`, registry.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/library/alpine/manifests/3.22":
+			w.Header().Set("Docker-Content-Digest", digest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+
+	resolver := &Resolver{Registry: registry.URL, Client: registry.Client()}
+
+	got, err := resolver.Resolve(context.Background(), "alpine:3.22")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got != digest {
+		t.Errorf("Expected digest %s, got %s", digest, got)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), "alpine:0.1"); err == nil {
+		t.Error("Expected an error for an unknown tag")
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref        string
+		base       string
+		repository string
+		tag        string
+	}{
+		{"golang:1.22-alpine", "https://registry-1.docker.io", "library/golang", "1.22-alpine"},
+		{"bitnami/redis", "https://registry-1.docker.io", "bitnami/redis", "latest"},
+		{"gcr.io/distroless/static-debian12:nonroot", "https://gcr.io", "distroless/static-debian12", "nonroot"},
+		{"localhost:5000/team/app:1.0", "https://localhost:5000", "team/app", "1.0"},
+	}
+
+	resolver := &Resolver{}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			base, repository, tag := resolver.parseReference(tc.ref)
+			if base != tc.base || repository != tc.repository || tag != tc.tag {
+				t.Errorf("Expected %s %s %s, got %s %s %s", tc.base, tc.repository, tc.tag, base, repository, tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package imagelock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// dockerHubRegistry serves images without a registry host, such as golang or library/alpine
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// manifestMediaTypes prefers multi-platform indexes, so the digest pins every architecture
+	manifestMediaTypes = "application/vnd.oci.image.index.v1+json, " +
+		"application/vnd.docker.distribution.manifest.list.v2+json, " +
+		"application/vnd.oci.image.manifest.v1+json, " +
+		"application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// Resolver looks up manifest digests with the registry HTTP API v2
+type Resolver struct {
+	// Registry, when set, is the base URL every lookup goes to instead of the image's own registry,
+	// e.g. http://localhost:5000 for a local mirror
+	Registry string
+
+	Client *http.Client
+}
+
+// Resolve returns the digest of the manifest ref points to
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	base, repository, tag := r.parseReference(ref)
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", base, repository, tag)
+
+	token := ""
+	resp, err := r.do(ctx, http.MethodHead, manifestURL, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", ref, err)
+	}
+	resp.Body.Close()
+
+	// Registries that require auth, even for anonymous pulls, answer with a bearer challenge
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err = r.token(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate for %s: %v", ref, err)
+		}
+
+		resp, err = r.do(ctx, http.MethodHead, manifestURL, token)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %v", ref, err)
+		}
+		resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s: registry returned %s", ref, resp.Status)
+	}
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	return r.digestFromBody(ctx, manifestURL, token, ref)
+}
+
+// digestFromBody hashes the manifest for registries that do not send Docker-Content-Digest
+func (r *Resolver) digestFromBody(ctx context.Context, manifestURL, token, ref string) (string, error) {
+	resp, err := r.do(ctx, http.MethodGet, manifestURL, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch manifest for %s: %v", ref, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch manifest for %s: registry returned %s", ref, resp.Status)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read manifest for %s: %v", ref, err)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (r *Resolver) do(ctx context.Context, method, target, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", manifestMediaTypes)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
+
+// token fetches an anonymous pull token for a Bearer realm="...",service="...",scope="..." challenge
+func (r *Resolver) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, ok := strings.Cut(challenge, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported auth challenge %q", challenge)
+	}
+
+	values := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+
+	if realm == "" {
+		return "", fmt.Errorf("auth challenge %q has no realm", challenge)
+	}
+
+	resp, err := r.do(ctx, http.MethodGet, realm+"?"+values.Encode(), "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token: %v", err)
+	}
+
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseReference splits ref into the registry base URL, the repository and the tag. Images without
+// a registry host come from Docker Hub, where official images live under library/.
+func (r *Resolver) parseReference(ref string) (base, repository, tag string) {
+	repository, tag = ref, "latest"
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repository, tag = ref[:i], ref[i+1:]
+	}
+
+	host := dockerHubRegistry
+	if first, rest, ok := strings.Cut(repository, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repository = first, rest
+	} else if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	base = "https://" + host
+	if r.Registry != "" {
+		base = strings.TrimSuffix(r.Registry, "/")
+	}
+
+	return base, repository, tag
+}